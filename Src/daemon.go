@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aspnmy/ollama_scanner/config"
+	"github.com/aspnmy/ollama_scanner/daemon"
+	"github.com/aspnmy/ollama_scanner/scanner"
+	"github.com/aspnmy/ollama_scanner/sink"
+	"github.com/aspnmy/ollama_scanner/store"
+	"github.com/aspnmy/ollama_scanner_envmanager"
+)
+
+const defaultConfigFile = "config.yml"
+
+// runDaemon 实现 daemon 子命令:按 config.yml 中的计划定期扫描.
+// 支持 systemd 的 Type=notify:启动完成后发送 READY=1,SIGHUP 重新加载配置,
+// SIGTERM 停止调度并等待进行中的任务结束.返回值为进程退出码.
+func runDaemon(args []string) int {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	configFile := fs.String("config", envOrDefault("CONFIG_FILE", defaultConfigFile), "包含 daemon 配置段的 YAML 文件")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	logCloser := setupLogger()
+	defer logCloser.Close()
+	if err := config.InitTimeZone(os.Getenv("TIMEZONE")); err != nil {
+		slog.Warn("初始化时区失败", "error", err)
+	}
+	cfg, err := loadDaemonConfig(*configFile)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	st, err := store.Open(cfg.StoreDir)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	defer st.Close()
+
+	d := daemon.New(cfg, st, runDaemonJob, slog.Default(), config.Location())
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		for sig := range sigCh {
+			if sig == syscall.SIGHUP {
+				reloadDaemon(d, *configFile)
+				continue
+			}
+			if ctx.Err() != nil {
+				fmt.Println("❌ 再次收到终止信号，取消进行中的任务")
+				d.Cancel()
+				continue
+			}
+			fmt.Println("⚠️ 收到终止信号，停止调度并等待进行中的任务结束...")
+			daemon.Notify("STOPPING=1")
+			stop()
+		}
+	}()
+
+	fmt.Printf("🕒 守护进程已启动，共 %d 个任务，结果保存在 %s\n", len(cfg.Jobs), cfg.StoreDir)
+	daemon.Notify("READY=1")
+	d.Run(ctx)
+	d.Drain(cfg.DrainTimeout)
+	fmt.Println("✅ 守护进程已退出")
+	return 0
+}
+
+// loadDaemonConfig 读取任务配置,并确认每个任务应用覆盖项后的扫描配置可用.
+// 只有任务需要 zmap 发现目标时才检查并安装 zmap
+func loadDaemonConfig(path string) (daemon.Config, error) {
+	cfg, err := daemon.LoadConfig(path)
+	if err != nil {
+		return daemon.Config{}, err
+	}
+	if len(cfg.Jobs) == 0 {
+		if _, err := prepareScanConfig(); err != nil {
+			return daemon.Config{}, err
+		}
+	}
+	var zmapJob string
+	for _, job := range cfg.Jobs {
+		scanCfg, err := prepareScanConfig(jobOverrides(job))
+		if err != nil {
+			return daemon.Config{}, fmt.Errorf("任务 %s: %w", job.Name, err)
+		}
+		if zmapJob == "" && usesZmap(scanCfg) {
+			zmapJob = job.Name
+		}
+	}
+	if zmapJob != "" {
+		if err := checkAndInstallZmap(); err != nil {
+			return daemon.Config{}, fmt.Errorf("任务 %s: 初始化扫描器失败: %w", zmapJob, err)
+		}
+	}
+	return cfg, nil
+}
+
+// usesZmap 判断扫描配置是否通过 zmap 发现目标,原生发现、masscan 和导入清单不需要 zmap
+func usesZmap(cfg scanner.Config) bool {
+	return cfg.ImportFile == "" && (cfg.ScannerType == "" || cfg.ScannerType == "zmap")
+}
+
+// reloadDaemon 重新加载 .env 和任务配置,失败时保留原有配置
+func reloadDaemon(d *daemon.Daemon, path string) {
+	daemon.Notify("RELOADING=1")
+	defer daemon.Notify("READY=1")
+
+	if err := envmanager.ReloadEnv(); err != nil {
+		slog.Error("重新加载环境变量失败，保留原有配置", "error", err)
+		return
+	}
+	cfg, err := loadDaemonConfig(path)
+	if err != nil {
+		slog.Error("重新加载配置失败，保留原有配置", "error", err)
+		fmt.Printf("⚠️ 重新加载配置失败，保留原有配置: %v\n", err)
+		return
+	}
+	d.Reload(cfg)
+	slog.Info("已重新加载配置", "jobs", len(cfg.Jobs))
+	fmt.Printf("🔄 已重新加载配置，共 %d 个任务\n", len(cfg.Jobs))
+}
+
+// jobOverrides 返回把任务中的覆盖项应用到扫描配置的函数
+func jobOverrides(job daemon.Job) func(*scanner.Config) {
+	return func(cfg *scanner.Config) {
+		// 任务中的目标来源优先于环境变量中的导入清单
+		if job.InputFile != "" {
+			cfg.InputFile = job.InputFile
+			cfg.ImportFile = ""
+		}
+		if job.ImportFile != "" {
+			cfg.ImportFile = job.ImportFile
+		}
+		if job.ScannerType != "" {
+			cfg.ScannerType = job.ScannerType
+		}
+		if job.ModelFilter != nil {
+			cfg.ModelFilter = *job.ModelFilter
+		}
+		if job.DisableBench != nil {
+			cfg.DisableBench = *job.DisableBench
+		}
+	}
+}
+
+// runDaemonJob 以环境变量中的扫描配置为基础,应用任务中的覆盖项并校验后执行一次扫描
+func runDaemonJob(ctx context.Context, job daemon.Job, out sink.Sink) error {
+	cfg, err := loadScanConfig(jobOverrides(job))
+	if err != nil {
+		return err
+	}
+	cfg.Logger = slog.Default().With("job", job.Name)
+
+	s := scanner.New(cfg)
+	results, err := s.Run(ctx)
+	if err != nil {
+		return err
+	}
+	for res := range results {
+		if err := out.Write(res); err != nil {
+			cfg.Logger.Warn("写入结果失败", "error", err)
+		}
+	}
+	return s.Err()
+}
+
+// envOrDefault 返回环境变量的值,未设置或为空时返回默认值
+func envOrDefault(key string, defaultVal string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultVal
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/bench"
+	"github.com/aspnmy/ollama_scanner/config"
+	"github.com/aspnmy/ollama_scanner/probe"
+	"github.com/aspnmy/ollama_scanner/target"
+)
+
+// runLoadTest 实现 loadtest 子命令:对自有端点列表中的每个端点逐级增加并发请求,
+// 报告每个级别的总生成速度、延迟分位数和错误率.返回值为进程退出码.
+func runLoadTest(args []string) int {
+	fs := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	owned := fs.String("owned", os.Getenv("BENCH_OWNED_FILE"), "自有端点列表,每行一个地址、主机名或 地址:端口")
+	model := fs.String("model", "", "压测的模型名称")
+	maxConcurrency := fs.Int("max-concurrency", bench.DefaultMaxConcurrency, fmt.Sprintf("最大并发请求数,按 1、2、4 … 逐级增加,最多 %d", bench.MaxConcurrency))
+	rounds := fs.Int("rounds", bench.DefaultLoadRounds, "每个级别中每个并发用户依次发送的请求数")
+	threshold := fs.Float64("error-threshold", bench.DefaultErrorThreshold, "错误率超过该值(0-1)时停止加压")
+	output := fs.String("o", "", "JSON 报告输出文件,为空时不输出")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: ollama_scanner loadtest -model qwen2.5:7b [-owned owned.txt] [-max-concurrency 16] [-o report.json]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	switch {
+	case *model == "":
+		fmt.Println("❌ 必须通过 -model 指定压测的模型")
+		return 2
+	case *owned == "":
+		fmt.Println("❌ 必须通过 -owned 或 BENCH_OWNED_FILE 指定自有端点列表")
+		return 2
+	case *maxConcurrency <= 0 || *maxConcurrency > bench.MaxConcurrency:
+		fmt.Printf("❌ -max-concurrency 必须在 1 到 %d 之间\n", bench.MaxConcurrency)
+		return 2
+	case *rounds <= 0 || *rounds > bench.MaxLoadRounds:
+		fmt.Printf("❌ -rounds 必须在 1 到 %d 之间\n", bench.MaxLoadRounds)
+		return 2
+	case *threshold <= 0 || *threshold > 1:
+		fmt.Println("❌ -error-threshold 必须在 0 到 1 之间")
+		return 2
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	setupSignalHandler(stop, "正在停止压测并写入报告")
+	resolveCtx, cancel := context.WithTimeout(ctx, inputResolveTimeout)
+	targets, err := target.ReadFile(resolveCtx, *owned)
+	cancel()
+	if err != nil {
+		fmt.Printf("❌ 读取自有端点列表失败:\n%v\n", err)
+		return 1
+	}
+	// 压测只针对明确列出的端点,不展开网段
+	var prefixes []netip.Prefix
+	for _, t := range targets {
+		if !t.Single() {
+			fmt.Printf("❌ %s:%d: 压测目标必须是单个地址或主机名: %s\n", *owned, t.Line, t)
+			return 1
+		}
+		prefixes = append(prefixes, t.Prefix)
+	}
+	if len(targets) == 0 {
+		fmt.Printf("❌ %s 中没有端点\n", *owned)
+		return 1
+	}
+
+	var v config.Validator
+	port := v.Int("OLLAMA_PORT", defaultPort)
+	sc := loadScope(&v)
+	benchCfg := bench.Config{
+		Prompt:         envOrDefault("benchPrompt", bench.DefaultPrompt),
+		NumPredict:     v.Int("BENCH_NUM_PREDICT", bench.DefaultNumPredict),
+		Timeout:        v.Duration("BENCH_TIMEOUT", bench.DefaultTimeout),
+		Owned:          prefixes,
+		MaxModelSizeGB: v.Float("BENCH_MAX_MODEL_SIZE_GB", 0),
+	}
+	if err := v.Err(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	// 与扫描相同,发送任何请求前确认所有端点都在授权范围内且不在拒绝列表中
+	if err := sc.Check(prefixes); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	for _, t := range targets {
+		if !sc.Contains(t.Prefix.Addr().String()) {
+			fmt.Printf("❌ %s:%d: 压测目标在拒绝列表中: %s\n", *owned, t.Line, t)
+			return 1
+		}
+	}
+	// 并发由 -max-concurrency 控制,连接数上限需要容纳全部并发请求,不经过按网段的限速
+	prober := &probe.Prober{
+		Port:    port,
+		Timeout: timeout,
+		Client: probe.NewClient(probe.ClientConfig{
+			MaxIdleConnsPerHost: *maxConcurrency,
+			MaxConnsPerHost:     *maxConcurrency,
+		}),
+		Limiter: probe.NewLimiter(probe.LimitConfig{}),
+	}
+	b := bench.New(benchCfg, prober)
+	cfg := bench.LoadConfig{Model: *model, MaxConcurrency: *maxConcurrency, Rounds: *rounds, ErrorThreshold: *threshold}
+
+	var (
+		reports []bench.LoadReport
+		failed  bool
+	)
+	for _, t := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		p := prober
+		if t.Port != 0 && t.Port != port {
+			p = prober.WithPort(t.Port)
+		}
+		tb := &bench.Benchmarker{Config: b.Config, Prober: p}
+		ip := t.Prefix.Addr().String()
+		fmt.Printf("\n🔥 压测 %s 模型 %s\n", t, *model)
+		if err := checkLoadModel(ctx, tb, ip, *model); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%6s %6s %7s %12s %28s %28s\n", "并发", "请求", "错误率", "总tokens/s", "延迟 P50/P90/P99", "首Token P50/P90/P99")
+		rep, err := tb.LoadTest(ctx, ip, cfg, printLevel)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			failed = true
+			continue
+		}
+		if rep.Stopped != "" {
+			fmt.Printf("⚠️ 停止加压: %s\n", rep.Stopped)
+		}
+		reports = append(reports, rep)
+	}
+
+	if *output != "" {
+		data, _ := json.MarshalIndent(reports, "", "  ")
+		if err := os.WriteFile(*output, append(data, '\n'), 0644); err != nil {
+			fmt.Printf("❌ 写入报告失败: %v\n", err)
+			return 1
+		}
+		fmt.Printf("📝 压测报告已写入 %s\n", *output)
+	}
+	if failed || ctx.Err() != nil {
+		return 1
+	}
+	return 0
+}
+
+// checkLoadModel 确认端点上存在该模型且模型大小不超过性能测试上限
+func checkLoadModel(ctx context.Context, b *bench.Benchmarker, ip string, model string) error {
+	models, err := b.Prober.Models(ctx, ip)
+	if err != nil {
+		return fmt.Errorf("获取模型列表失败: %w", err)
+	}
+	for _, m := range models {
+		if m.Name == model {
+			if reason := b.SkipReason(ip, m.Size); reason != "" {
+				return fmt.Errorf("%s", reason)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("端点上没有模型 %s", model)
+}
+
+// printLevel 打印一个并发级别的结果
+func printLevel(l bench.Level) {
+	ms := func(d time.Duration) string { return strconv.FormatInt(d.Milliseconds(), 10) }
+	fmt.Printf("%6d %6d %6.1f%% %12.1f %28s %28s\n", l.Concurrency, l.Requests, l.ErrorRate*100, l.TokensPerSec,
+		strings.Join([]string{ms(l.LatencyP50), ms(l.LatencyP90), ms(l.LatencyP99)}, "/")+" ms",
+		strings.Join([]string{ms(l.FirstTokenP50), ms(l.FirstTokenP90), ms(l.FirstTokenP99)}, "/")+" ms")
+	for status, n := range l.ErrorStatuses {
+		fmt.Printf("       └─ %s: %d\n", status, n)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aspnmy/ollama_scanner/scanner"
+	"github.com/aspnmy/ollama_scanner/sink"
+)
+
+// runMerge 实现 merge 子命令:合并多个分片的 JSON 结果,同一地址保留最新的结果,
+// 并检查分片是否齐全.返回值为进程退出码.
+func runMerge(args []string) int {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	output := fs.String("o", "merged.json", "合并后的 JSON Lines 输出文件")
+	csvOutput := fs.String("csv", "", "同时输出 CSV 文件,为空时不输出")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: ollama_scanner merge [-o merged.json] [-csv merged.csv] shard1.json shard2.json ...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var all []scanner.ScanResult
+	for _, path := range fs.Args() {
+		results, err := sink.ReadJSON(path)
+		if err != nil {
+			fmt.Printf("❌ 读取 %s 失败: %v\n", path, err)
+			return 1
+		}
+		fmt.Printf("📄 %s: %d 个主机\n", path, len(results))
+		all = append(all, results...)
+	}
+	for _, w := range checkShards(all) {
+		fmt.Printf("⚠️ %s\n", w)
+	}
+
+	merged, duplicates := scanner.MergeResults(all)
+	opts := sink.Options{}
+	for _, res := range merged {
+		for _, m := range res.Models {
+			opts.Bench = opts.Bench || !m.BenchmarkedAt.IsZero()
+			opts.BenchStats = opts.BenchStats || m.Bench != nil
+			opts.Smoke = opts.Smoke || m.Smoke != nil
+			opts.Policy = opts.Policy || m.Violations != nil
+			opts.Details = opts.Details || m.Details != nil
+		}
+	}
+	jsonOut, err := sink.NewJSON(*output)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	out := sink.Multi{jsonOut}
+	if *csvOutput != "" {
+		csvOut, err := sink.NewCSV(*csvOutput, opts)
+		if err != nil {
+			jsonOut.Close()
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		out = append(out, csvOut)
+	}
+	for _, res := range merged {
+		if err := out.Write(res); err != nil {
+			out.Close()
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+	}
+	if err := out.Close(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	fmt.Printf("✅ 合并完成: %d 个主机，去除重复 %d 条，结果已写入 %s\n", len(merged), duplicates, jsonOut.Name())
+	return 0
+}
+
+// checkShards 检查结果中的分片是否属于同一分片方案且没有缺失
+func checkShards(results []scanner.ScanResult) []string {
+	seen := map[int]map[int]bool{}
+	for _, res := range results {
+		shard, err := scanner.ParseShard(res.Shard)
+		if err != nil || !shard.Enabled() {
+			continue
+		}
+		if seen[shard.Count] == nil {
+			seen[shard.Count] = map[int]bool{}
+		}
+		seen[shard.Count][shard.Index] = true
+	}
+
+	var warnings []string
+	counts := slices.Sorted(maps.Keys(seen))
+	if len(counts) > 1 {
+		warnings = append(warnings, fmt.Sprintf("结果来自不同的分片总数 %v,可能混入了其他扫描", counts))
+	}
+	for _, n := range counts {
+		var missing []string
+		for i := 1; i <= n; i++ {
+			if !seen[n][i] {
+				missing = append(missing, fmt.Sprintf("%d/%d", i, n))
+			}
+		}
+		// 没有发现主机的分片也不会产生结果,缺失只作为提示
+		if len(missing) > 0 {
+			warnings = append(warnings, fmt.Sprintf("没有以下分片的结果,请确认对应实例已完成: %s", strings.Join(missing, ", ")))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/mock"
+)
+
+// runMockServer 实现 mock-server 子命令:在本机启动若干个模拟 Ollama 实例,
+// 直到收到终止信号.返回值为进程退出码.
+func runMockServer(args []string) int {
+	fs := flag.NewFlagSet("mock-server", flag.ContinueOnError)
+	count := fs.Int("count", 1, "启动的模拟实例数量")
+	host := fs.String("host", "127.0.0.1", "监听地址")
+	port := fs.Int("port", defaultPort, "第一个实例的端口,后续实例端口依次加一")
+	spread := fs.Bool("spread-hosts", false, "所有实例使用同一端口,监听地址依次为 127.0.0.1、127.0.0.2 …(仅 Linux),便于按网段扫描")
+	models := fs.String("models", strings.Join(mock.DefaultModels, ","), "逗号分隔的模型列表,第一个模型视为已加载")
+	version := fs.String("version", mock.DefaultVersion, "/api/version 返回的版本号")
+	latency := fs.Duration("latency", 0, "每个请求响应前的额外延迟")
+	tps := fs.Float64("tps", mock.DefaultTokensPerSec, "生成接口每秒输出的 token 数")
+	token := fs.String("token", "", "非空时要求请求携带 Authorization: Bearer <token>")
+	failures := fs.String("failure", "", "逗号分隔的故障模式,按实例轮流分配: none、slow-headers、malformed-json、endless-stream")
+	slowHeaders := fs.Duration("slow-header-delay", mock.DefaultSlowHeaderDelay, "slow-headers 模式下发送响应头前的等待时间")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *count <= 0 {
+		fmt.Println("❌ -count 必须为正整数")
+		return 2
+	}
+	modes, err := parseFailures(*failures)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 2
+	}
+	var modelList []mock.Model
+	for _, name := range strings.Split(*models, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		m := mock.NewModel(name)
+		m.Running = len(modelList) == 0
+		modelList = append(modelList, m)
+	}
+	// 模型列表为空时 mock.Config 会改用默认模型,这里直接拒绝
+	if len(modelList) == 0 {
+		fmt.Println("❌ -models 中没有任何模型名称")
+		return 2
+	}
+
+	var servers []*mock.Server
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, s := range servers {
+			s.Shutdown(ctx)
+		}
+	}()
+	for i := range *count {
+		addr := net.JoinHostPort(*host, strconv.Itoa(*port+i))
+		if *spread {
+			ip, err := nthLoopback(i)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return 1
+			}
+			addr = net.JoinHostPort(ip, strconv.Itoa(*port))
+		}
+		cfg := mock.Config{
+			Models:          modelList,
+			Version:         *version,
+			Latency:         *latency,
+			TokensPerSec:    *tps,
+			Token:           *token,
+			Failure:         modes[i%len(modes)],
+			SlowHeaderDelay: *slowHeaders,
+		}
+		s, err := mock.Start(addr, cfg)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		servers = append(servers, s)
+		mode := string(cfg.Failure)
+		if mode == "" {
+			mode = "none"
+		}
+		fmt.Printf("🧪 模拟实例 %d: %s (故障模式: %s)\n", i+1, s.URL(), mode)
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	fmt.Println("按 Ctrl-C 停止模拟服务")
+	<-sigCh
+	fmt.Println("\n正在停止模拟服务...")
+	return 0
+}
+
+// parseFailures 解析逗号分隔的故障模式列表,至少返回一个元素
+func parseFailures(s string) ([]mock.Failure, error) {
+	var modes []mock.Failure
+	for _, part := range strings.Split(s, ",") {
+		f, err := mock.ParseFailure(part)
+		if err != nil {
+			return nil, err
+		}
+		modes = append(modes, f)
+	}
+	return modes, nil
+}
+
+// nthLoopback 返回 127.0.0.0/8 中的第 i+1 个地址
+func nthLoopback(i int) (string, error) {
+	if i >= 254 {
+		return "", fmt.Errorf("-spread-hosts 最多支持 254 个实例")
+	}
+	return fmt.Sprintf("127.0.0.%d", i+1), nil
+}
@@ -9,29 +9,29 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
+	"cmp"
 	"context"
-	"encoding/csv"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net"
-	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"runtime"
-	"sort"
-	"strconv"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/aspnmy/ollama_scanner/bench"
+	"github.com/aspnmy/ollama_scanner/config"
+	"github.com/aspnmy/ollama_scanner/logging"
+	"github.com/aspnmy/ollama_scanner/scanner"
+	"github.com/aspnmy/ollama_scanner/sink"
 	"github.com/aspnmy/ollama_scanner_envmanager"
 )
 
@@ -41,11 +41,14 @@ const (
 	maxWorkers         = 200
 	maxIdleConns       = 100
 	idleConnTimeout    = 90 * time.Second
-	benchTimeout       = 30 * time.Second
 	defaultCSVFile     = "results.csv"
+	defaultStateFile   = "scan_state.json"
+	defaultLogFile     = "scan.log"
+	defaultLogDir      = "logs"
 	defaultZmapThreads = 10   // zmap 默认线程数
 	defaultMasscanRate = 1000 // masscan 默认扫描速率
-	defaultBenchPrompt = "为什么太阳会发光？用一句话回答"
+	// exitPolicyViolation 为扫描完成但有模型违反合规策略时的退出码
+	exitPolicyViolation = 3
 )
 
 // init 函数放在最上方
@@ -61,18 +64,6 @@ func init() {
 	}
 }
 
-type ScanResult struct {
-	IP     string
-	Models []ModelInfo
-}
-
-type ModelInfo struct {
-	Name            string
-	FirstTokenDelay time.Duration
-	TokensPerSec    float64
-	Status          string
-}
-
 // 添加获取MAC地址的函数
 func getEth0MAC() (string, error) {
 	ifaces, err := net.Interfaces()
@@ -126,23 +117,68 @@ func (p *Progress) printProgress() {
 }
 
 var (
-	resultsChan chan ScanResult
-	csvFile     *os.File
-	csvWriter   *csv.Writer
+	modelDetailsFlag = flag.Bool("model-details", false, "通过 /api/show 获取每个模型的许可证、参数量、量化等信息(每个模型额外一次请求)")
+	importFlag       = flag.String("import", "", "从 Nmap XML、CSV 或 JSON 清单导入目标,跳过目标发现直接探测")
+	importFormatFlag = flag.String("import-format", "", "导入清单格式: nmap、csv 或 json,默认按扩展名判断")
+	importColumnFlag = flag.String("import-column", "", "CSV 中地址所在的列名或 JSON 对象中的地址字段,默认 ip")
+	shardFlag        = flag.String("shard", "", "分片扫描,格式为 序号/总数,例如 2/5 表示 5 个实例中的第 2 个")
+	smokeFlag        = flag.String("smoke", "", "冒烟测试文件,对自有端点上的每个生成模型执行用例并检查回复")
+	policyFlag       = flag.String("policy", "", "合规策略文件,有模型违反策略时以退出码 3 结束")
 )
 
 // main 函数是程序的入口点,负责初始化程序、检查并安装 zmap、设置信号处理和启动扫描过程.
 func main() {
+	// 子命令在解析扫描参数之前分发
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "mock-server":
+			os.Exit(runMockServer(os.Args[2:]))
+		case "daemon":
+			os.Exit(runDaemon(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		case "merge":
+			os.Exit(runMerge(os.Args[2:]))
+		case "loadtest":
+			os.Exit(runLoadTest(os.Args[2:]))
+		}
+	}
+
 	// 解析命令行参数
 	flag.Parse()
+	if *modelDetailsFlag {
+		config.SetFromFlag("ENABLE_MODEL_DETAILS", "model-details", "true")
+	}
+	for key, f := range map[string]struct{ name, value string }{
+		"IMPORT_FILE":      {"import", *importFlag},
+		"IMPORT_FORMAT":    {"import-format", *importFormatFlag},
+		"IMPORT_COLUMN":    {"import-column", *importColumnFlag},
+		"SHARD":            {"shard", *shardFlag},
+		"BENCH_SMOKE_FILE": {"smoke", *smokeFlag},
+		"POLICY_FILE":      {"policy", *policyFlag},
+	} {
+		if f.value != "" {
+			config.SetFromFlag(key, f.name, f.value)
+		}
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	resultsChan = make(chan ScanResult, 100)
+	// 日志与面向用户的进度显示分开,默认写入 LOG_PATH 指定的文件
+	logCloser := setupLogger()
+	defer logCloser.Close()
+
+	// 结果中的时间戳使用 TIMEZONE 配置的时区
+	if err := config.InitTimeZone(os.Getenv("TIMEZONE")); err != nil {
+		slog.Warn("初始化时区失败", "error", err)
+	}
 
-	// 初始化扫描器
-	if err := checkAndInstallZmap(); err != nil {
-		log.Printf("❌ 初始化扫描器失败: %v\n", err)
+	// 初始化扫描器,原生发现和导入清单不需要 zmap
+	if os.Getenv("scannerType") == "native" || os.Getenv("IMPORT_FILE") != "" {
+		slog.Info("不使用 zmap 进行目标发现，跳过 zmap 检查")
+	} else if err := checkAndInstallZmap(); err != nil {
+		slog.Error("初始化扫描器失败", "error", err)
+		fmt.Printf("❌ 初始化扫描器失败: %v\n", err)
 		fmt.Printf("是否继续执行程序？(y/n): ")
 		var answer string
 		fmt.Scanln(&answer)
@@ -151,15 +187,30 @@ func main() {
 		}
 	}
 
-	// 初始化 CSV 写入器,用于将扫描结果保存到文件中
-	initCSVWriter()
-	// 确保在函数退出时关闭 CSV 文件
-	defer csvFile.Close()
-	// 设置信号处理,以便在收到终止信号时清理资源并退出程序
-	setupSignalHandler(cancel)
+	// 在创建输出文件和发送任何数据包之前完成配置校验,所有问题一次性列出
+	cfg, err := prepareScanConfig()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	// 初始化终端、CSV 和 JSON 输出
+	out := openSinks()
+	// 设置信号处理,收到终止信号时取消扫描,已产生的结果写入输出后再退出
+	setupSignalHandler(cancel, "正在停止扫描并保存进度")
 	// 启动扫描过程,如果扫描失败则打印错误信息
-	if err := runScanProcess(ctx); err != nil {
+	nonCompliant, err := runScanProcess(ctx, cfg, out)
+	if cerr := out.Close(); cerr != nil {
+		fmt.Printf("⚠️ 关闭输出文件失败: %v\n", cerr)
+	}
+	if err != nil {
 		fmt.Printf("❌ 扫描失败: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("\n✅ 扫描完成")
+	if nonCompliant > 0 {
+		fmt.Printf("⛔ %d 个模型违反合规策略\n", nonCompliant)
+		os.Exit(exitPolicyViolation)
 	}
 }
 
@@ -171,22 +222,24 @@ func checkAndInstallZmap() error {
 	_, err := exec.LookPath("zmap")
 	if err == nil {
 		// zmap 已安装
-		log.Println("zmap 已安装")
+		slog.Info("zmap 已安装")
 		return nil
 	}
 
 	// zmap 未安装,尝试自动安装
-	log.Println("zmap 未安装, 尝试自动安装...")
+	slog.Info("zmap 未安装, 尝试自动安装...")
 	var installErr error
 	// 获取当前操作系统名称
 	osName := runtime.GOOS
-	log.Printf("Operating System: %s\n", osName)
+	slog.Info("检测操作系统", "os", osName)
 
-	// 打印当前环境变量,方便调试
-	log.Println("当前环境变量:")
+	// 记录当前环境变量,方便调试;只记录变量名,避免将令牌等敏感值写入日志文件
+	var envKeys []string
 	for _, env := range os.Environ() {
-		log.Println(env)
+		key, _, _ := strings.Cut(env, "=")
+		envKeys = append(envKeys, key)
 	}
+	slog.Debug("当前环境变量", "keys", envKeys)
 
 	// 根据不同的操作系统选择不同的安装方式
 	switch osName {
@@ -199,7 +252,7 @@ func checkAndInstallZmap() error {
 			cmd := exec.Command("sudo", "-u", "root", "/usr/bin/apt-get", "update")
 			installErr = cmd.Run()
 			if installErr != nil {
-				log.Printf("apt-get update failed: %v\n", installErr)
+				slog.Error("apt-get update failed", "error", installErr)
 				return fmt.Errorf("apt-get update failed: %w", installErr)
 			}
 
@@ -207,7 +260,7 @@ func checkAndInstallZmap() error {
 			cmd = exec.Command("sudo", "-u", "root", "/usr/bin/apt-get", "install", "-y", "zmap")
 			installErr = cmd.Run()
 			if installErr != nil {
-				log.Printf("apt-get install zmap failed: %v\n", installErr)
+				slog.Error("apt-get install zmap failed", "error", installErr)
 				return fmt.Errorf("apt-get install zmap failed: %w", installErr)
 			}
 
@@ -219,7 +272,7 @@ func checkAndInstallZmap() error {
 				cmd := exec.Command("sudo", "-u", "root", "/usr/bin/yum", "install", "-y", "zmap")
 				installErr = cmd.Run()
 				if installErr != nil {
-					log.Printf("yum install zmap failed: %v\n", installErr)
+					slog.Error("yum install zmap failed", "error", installErr)
 					return fmt.Errorf("yum install zmap failed: %w", installErr)
 				}
 
@@ -243,219 +296,145 @@ func checkAndInstallZmap() error {
 		return fmt.Errorf("不支持的操作系统: %s，无法自动安装 zmap。请手动安装", osName)
 	}
 
-	log.Println("zmap 安装完成")
+	slog.Info("zmap 安装完成")
 	return nil
 }
 
-// initCSVWriter 函数用于初始化 CSV 写入器,创建 CSV 文件并写入表头.
-func initCSVWriter() {
-	var err error
+// setupLogger 根据 ENABLE_LOG、LOG_LEVEL、LOG_FORMAT 和 LOG_PATH 初始化默认日志记录器,
+// LOG_PATH 为空时写入 LOG_DIR(默认 logs)下的 scan.log,避免与终端输出交错;
+// LOG_PATH=stderr 时写入标准错误输出
+func setupLogger() io.Closer {
+	logPath := os.Getenv("LOG_PATH")
+	switch logPath {
+	case "":
+		logPath = filepath.Join(cmp.Or(os.Getenv("LOG_DIR"), defaultLogDir), defaultLogFile)
+	case "stderr":
+		logPath = ""
+	}
+
+	logger, closer, err := logging.New(logging.Config{
+		Enabled:    config.GetEnvAsBool("ENABLE_LOG", true),
+		Level:      os.Getenv("LOG_LEVEL"),
+		Format:     os.Getenv("LOG_FORMAT"),
+		Path:       logPath,
+		MaxSizeMB:  config.GetEnvAsInt("LOG_MAX_SIZE_MB", logging.DefaultMaxSizeMB),
+		MaxBackups: config.GetEnvAsInt("LOG_MAX_BACKUPS", logging.DefaultMaxBackups),
+	})
+	if err != nil {
+		fmt.Printf("⚠️ 初始化日志失败，日志将被丢弃: %v\n", err)
+		logger, closer = logging.Discard(), io.NopCloser(nil)
+	}
+	slog.SetDefault(logger)
+	return closer
+}
+
+// openSinks 根据 OUTPUT_FILE 和 JSON_OUTPUT_FILE 创建结果输出,文件创建失败时只给出警告
+func openSinks() sink.Multi {
+	opts := sink.Options{
+		Bench:   os.Getenv("disableBench") != "true",
+		Details: os.Getenv("ENABLE_MODEL_DETAILS") == "true",
+		Smoke:   os.Getenv("BENCH_SMOKE_FILE") != "",
+		Policy:  os.Getenv("POLICY_FILE") != "",
+	}
+	// 测试计划包含多次请求时才有统计值
+	opts.BenchStats = opts.Bench && (config.GetEnvAsInt("BENCH_RUNS", bench.DefaultRuns) > 1 ||
+		config.GetEnvAsInt("BENCH_WARMUP_RUNS", 0) > 0 || config.GetEnvAsBool("BENCH_COLD_START", false))
+	out := sink.Multi{sink.NewTerminal(os.Stdout, opts)}
 
 	// 获取输出文件路径
 	outputFile := os.Getenv("OUTPUT_FILE")
 	if outputFile == "" {
 		if err := envmanager.UpdateEnvironmentVariable("OUTPUT_FILE", defaultCSVFile); err != nil {
 			fmt.Printf("⚠️ 设置默认输出文件失败: %v\n", err)
-			return
 		}
 		outputFile = os.Getenv("OUTPUT_FILE")
 	}
-
-	// 如果路径不是绝对路径，则使用当前目录
-	if !filepath.IsAbs(outputFile) {
-		currentDir, err := os.Getwd()
+	if outputFile != "" {
+		csvSink, err := sink.NewCSV(outputFile, opts)
 		if err != nil {
-			fmt.Printf("⚠️ 获取当前目录失败: %v\n", err)
-			return
+			fmt.Printf("⚠️ %v\n", err)
+		} else {
+			out = append(out, csvSink)
+			fmt.Printf("📝 CSV文件已创建: %s\n", csvSink.Name())
 		}
-		outputFile = filepath.Join(currentDir, outputFile)
 	}
 
-	// 确保输出目录存在
-	outputDir := filepath.Dir(outputFile)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		fmt.Printf("⚠️ 创建输出目录失败: %v\n", err)
-		return
-	}
-
-	// 创建 CSV 文件
-	csvFile, err = os.Create(outputFile)
-	if err != nil {
-		fmt.Printf("⚠️ 创建CSV文件失败: %v\n", err)
-		return
+	// JSON_OUTPUT_FILE 为空时不输出 JSON
+	if jsonOutput := os.Getenv("JSON_OUTPUT_FILE"); jsonOutput != "" {
+		jsonSink, err := sink.NewJSON(jsonOutput)
+		if err != nil {
+			fmt.Printf("⚠️ %v\n", err)
+		} else {
+			out = append(out, jsonSink)
+			fmt.Printf("📝 JSON文件已创建: %s\n", jsonSink.Name())
+		}
 	}
 
-	// 创建 CSV 写入器并写入表头
-	csvWriter = csv.NewWriter(csvFile)
-	headers := []string{"IP地址", "模型名称", "状态"}
-	if os.Getenv("disableBench") != "true" {
-		headers = append(headers, "首Token延迟(ms)", "Tokens/s")
-	}
-	if err := csvWriter.Write(headers); err != nil {
-		fmt.Printf("⚠️ 写入CSV表头失败: %v\n", err)
-		return
+	// BENCH_SAMPLES_FILE 为空时原始样本只保存在 JSON 结果中
+	if samplesOutput := os.Getenv("BENCH_SAMPLES_FILE"); samplesOutput != "" && opts.BenchStats {
+		samplesSink, err := sink.NewSamples(samplesOutput)
+		if err != nil {
+			fmt.Printf("⚠️ %v\n", err)
+		} else {
+			out = append(out, samplesSink)
+			fmt.Printf("📝 样本文件已创建: %s\n", samplesSink.Name())
+		}
 	}
-
-	fmt.Printf("📝 CSV文件已创建: %s\n", outputFile)
+	return out
 }
 
-func setupSignalHandler(cancel context.CancelFunc) {
-	sigCh := make(chan os.Signal, 1)
+// setupSignalHandler 第一次收到终止信号时打印 action 并取消任务,第二次收到时立即强制退出
+func setupSignalHandler(cancel context.CancelFunc, action string) {
+	sigCh := make(chan os.Signal, 2)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-sigCh
+		fmt.Printf("\n⚠️ 收到终止信号，%s，再次按 Ctrl-C 强制退出...\n", action)
 		cancel()
-		fmt.Println("\n⚠️ 收到终止信号，正在保存进度...")
-		if csvWriter != nil {
-			csvWriter.Flush()
-		}
-		if csvFile != nil {
-			csvFile.Close()
-		}
+		<-sigCh
+		fmt.Println("\n❌ 再次收到终止信号，强制退出")
 		os.Exit(1)
 	}()
 }
 
-func runScanProcess(ctx context.Context) error {
-	// 先设置 MAC 地址
+// prepareScanConfig 补全网关 MAC 地址后加载并校验扫描配置
+func prepareScanConfig(overrides ...func(*scanner.Config)) (scanner.Config, error) {
 	if err := setupGatewayMAC(); err != nil {
-		return err
-	}
-
-	if err := validateInput(); err != nil {
-		return err
-	}
-	gatewayMAC := os.Getenv("GATEWAY_MAC")
-	fmt.Printf("🔍 开始扫描目标，使用网关MAC: %s\n", gatewayMAC)
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
-		// Start goroutine to handle results
-		go handleScanResults()
-		if err := execScan(); err != nil {
-			return err
-		}
-		close(resultsChan)
-		return nil
+		return scanner.Config{}, err
 	}
+	return loadScanConfig(overrides...)
 }
 
-// 简化后的 processScanResults 函数
-func processScanResults() error {
-	outputFile := os.Getenv("OUTPUT_FILE")
-	file, err := os.Open(outputFile)
-	if err != nil {
-		return fmt.Errorf("打开结果文件失败: %w", err)
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		ip := strings.TrimSpace(scanner.Text())
-		if checkPort(ip) && checkOllama(ip) {
-			result := ScanResult{IP: ip}
-			if models := getModels(ip); len(models) > 0 {
-				models = sortModels(models)
-				for _, model := range models {
-					info := ModelInfo{Name: model}
-					if os.Getenv("disableBench") != "true" {
-						latency, tps, status := benchmarkModel(ip, model, os.Getenv("benchPrompt"))
-						info.FirstTokenDelay = latency
-						info.TokensPerSec = tps
-						info.Status = status
-					} else {
-						info.Status = "发现"
-					}
-					result.Models = append(result.Models, info)
-				}
-				printResult(result)
-				writeCSV(result)
-			}
-		}
-	}
-
-	if csvWriter != nil {
-		csvWriter.Flush()
-	}
-
-	fmt.Printf("\n✅ 扫描完成，结果已保存到: %s\n", outputFile)
-	return nil
-}
+// runScanProcess 执行扫描并把结果写入输出,返回违反合规策略的模型数
+func runScanProcess(ctx context.Context, cfg scanner.Config, out sink.Sink) (int, error) {
+	fmt.Printf("🔒 授权范围: 负责人 %s，授权单号 %s\n", cfg.Scope.Owner, cfg.Scope.Authorization)
+	fmt.Printf("🔍 开始扫描目标，使用网关MAC: %s\n", cfg.GatewayMAC)
 
-func handleScanResults() {
-	for res := range resultsChan {
-		printResult(res)
-		writeCSV(res)
+	s := scanner.New(cfg)
+	results, err := s.Run(ctx)
+	if err != nil {
+		return 0, err
 	}
-}
-
-func printResult(res ScanResult) {
-	disableBench := os.Getenv("disableBench")
-	fmt.Printf("\nIP地址: %s\n", res.IP)
-	fmt.Println(strings.Repeat("-", 50))
-	for _, model := range res.Models {
-		fmt.Printf("├─ 模型: %-25s\n", model.Name)
-		if disableBench != "true" {
-			fmt.Printf("│ ├─ 状态: %s\n", model.Status)
-			fmt.Printf("│ ├─ 首Token延迟: %v\n", model.FirstTokenDelay.Round(time.Millisecond))
-			fmt.Printf("│ └─ 生成速度: %.1f tokens/s\n", model.TokensPerSec)
-		} else {
-			fmt.Printf("│ └─ 状态: %s\n", model.Status)
+	nonCompliant := 0
+	for res := range results {
+		nonCompliant += res.NonCompliant()
+		if err := out.Write(res); err != nil {
+			fmt.Printf("⚠️ %v\n", err)
 		}
-		fmt.Println(strings.Repeat("-", 50))
 	}
-}
 
-func writeCSV(res ScanResult) {
-	disableBench := os.Getenv("disableBench")
-	for _, model := range res.Models {
-		record := []string{res.IP, model.Name, model.Status}
-		if disableBench != "true" {
-			record = append(record,
-				fmt.Sprintf("%.0f", model.FirstTokenDelay.Seconds()*1000),
-				fmt.Sprintf("%.1f", model.TokensPerSec))
+	if ctx.Err() != nil {
+		stateFile := os.Getenv("STATE_FILE")
+		if stateFile == "" {
+			stateFile = defaultStateFile
 		}
-		if csvWriter != nil {
-			err := csvWriter.Write(record)
-			if err != nil {
-				fmt.Printf("⚠️ 写入CSV失败: %v\n", err) // Handle the error appropriately
-			}
-		}
-	}
-}
-
-func worker(ctx context.Context, wg *sync.WaitGroup, ips <-chan string) {
-	disableBench := os.Getenv("disableBench")
-	benchPrompt := os.Getenv("benchPrompt")
-	defer wg.Done()
-	for ip := range ips {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			if checkPort(ip) && checkOllama(ip) {
-				result := ScanResult{IP: ip}
-				if models := getModels(ip); len(models) > 0 {
-					models = sortModels(models)
-					for _, model := range models {
-						info := ModelInfo{Name: model}
-						if disableBench != "true" {
-							latency, tps, status := benchmarkModel(ip, model, benchPrompt)
-							info.FirstTokenDelay = latency
-							info.TokensPerSec = tps
-							info.Status = status
-						} else {
-							info.Status = "发现"
-						}
-						result.Models = append(result.Models, info)
-					}
-					resultsChan <- result
-				}
-			}
+		if err := s.State().Save(stateFile); err != nil {
+			fmt.Printf("⚠️ %v\n", err)
+		} else {
+			fmt.Printf("💾 扫描进度已保存到: %s\n", stateFile)
 		}
 	}
+	return nonCompliant, s.Err()
 }
 
 // 修改 setupGatewayMAC 函数使用新的环境变量更新函数
@@ -475,309 +454,23 @@ func setupGatewayMAC() error {
 	return nil
 }
 
-// 修改 validateInput 函数，移除 MAC 地址相关的逻辑
-func validateInput() error {
-	// 获取脚本所在目录
-	scriptDir, err := getScriptDir()
-	if err != nil {
-		return fmt.Errorf("获取脚本目录失败: %v", err)
-	}
-
-	// 优先使用命令行参数中的路径
-	// 如果命令行参数是相对路径且配置文件中有绝对路径，则使用配置文件中的路径
-	inputFile := os.Getenv("INPUT_FILE")
-	if !filepath.IsAbs(inputFile) {
-		inputFile = filepath.Join(scriptDir, inputFile)
-	}
-	log.Printf("使用输入文件: %s", inputFile)
-
-	outputFile := os.Getenv("OUTPUT_FILE")
-	if !filepath.IsAbs(outputFile) {
-		outputFile = filepath.Join(scriptDir, outputFile)
-	}
-	log.Printf("使用输出文件: %s", outputFile)
-
-	// 检查输入文件是否存在
-	if _, err := os.Stat(inputFile); os.IsNotExist(err) {
-		// 如果文件不存在，创建一个空文件
-		emptyFile, err := os.Create(inputFile)
-		if err != nil {
-			return fmt.Errorf("创建输入文件失败: %v", err)
-		}
-		emptyFile.Close()
-		log.Printf("创建了空的输入文件: %s", inputFile)
-		return fmt.Errorf("请在输入文件中添加要扫描的IP地址: %s", inputFile)
-	}
-
-	return nil
-}
-
-// 获取脚本所在目录的新函数
-func getScriptDir() (string, error) {
-	// 尝试使用 os.Executable() 获取可执行文件路径
-	execPath, err := os.Executable()
-	if err != nil {
-		return "", fmt.Errorf("获取可执行文件路径失败: %v", err)
-	}
-
-	// 获取可执行文件的实际路径（处理符号链接）
-	realPath, err := filepath.EvalSymlinks(execPath)
-	if err != nil {
-		return "", fmt.Errorf("解析符号链接失败: %v", err)
-	}
-
-	// 获取目录路径
-	dir := filepath.Dir(realPath)
-
-	// 验证目录是否存在
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		return "", fmt.Errorf("脚本目录不存在: %v", err)
-	}
-
-	return dir, nil
-}
-
-func execScan() error {
-	scannerType := os.Getenv("scannerType")
-	if scannerType == "masscan" {
-		return execMasscan()
-	}
-	return execZmap()
-}
-
-func execMasscan() error {
-	OLLAMA_PORT := os.Getenv("OLLAMA_PORT")
-	masscanRate := os.Getenv("masscanRate")
-	gatewayMAC := os.Getenv("GATEWAY_MAC")
-	inputFile := os.Getenv("INPUT_FILE")
-	outputFile := os.Getenv("OUTPUT_FILE")
-	cmd := exec.Command("masscan",
-		"-p", OLLAMA_PORT,
-		"--rate", masscanRate,
-		"--interface", "eth0",
-		"--source-ip", gatewayMAC,
-		"-iL", inputFile,
-		"-oL", outputFile)
-
-	out, err := cmd.CombinedOutput()
-	fmt.Printf("masscan 输出:\n%s\n", string(out))
-	return err
-}
-
-func execZmap() error {
-	OLLAMA_PORT := os.Getenv("OLLAMA_PORT")
-	zmapThreads := os.Getenv("zmapThreads")
-	gatewayMAC := strings.Trim(os.Getenv("GATEWAY_MAC"), "'") // 移除可能存在的单引号
-	inputFile := os.Getenv("INPUT_FILE")
-	outputFile := os.Getenv("OUTPUT_FILE")
-
-	// 打印调试信息
-	log.Printf("DEBUG: MAC地址: %s", gatewayMAC)
-	log.Printf("DEBUG: 完整命令: zmap -p %s -G %s -w %s -o %s -T %s",
-		OLLAMA_PORT, gatewayMAC, inputFile, outputFile, zmapThreads)
-
-	cmd := exec.Command("zmap",
-		"-p", fmt.Sprintf("%s", OLLAMA_PORT),
-		"-G", gatewayMAC,
-		"-w", inputFile,
-		"-o", outputFile,
-		"-T", fmt.Sprintf("%s", zmapThreads))
-
-	out, err := cmd.CombinedOutput()
-	fmt.Printf("zmap 输出:\n%s\n", string(out))
-	return err
-}
-
-func checkPort(ip string) bool {
-	OLLAMA_PORT := os.Getenv("OLLAMA_PORT")
-	if OLLAMA_PORT == "" {
-		if err := envmanager.UpdateEnvironmentVariable("OLLAMA_PORT", strconv.Itoa(defaultPort)); err != nil {
-			log.Printf("设置默认端口失败: %v", err)
-			return false
-		}
-		OLLAMA_PORT = os.Getenv("OLLAMA_PORT")
-	}
-	port, _ := strconv.Atoi(OLLAMA_PORT)
-	result := net.Dialer{Timeout: timeout}
-	conn, err := result.Dial("tcp", fmt.Sprintf("%s:%d", ip, port))
-	if err != nil {
-		return false
-	}
-	conn.Close()
-	//enableLog := os.Getenv("enableLog")
-	//if enableLog == "true" {
-	//	logger.LogScanResult(ip, nil, fmt.Sprintf("端口检查: %v", result))
-	//}
-	return true
-}
-
-func checkOllama(ip string) bool {
-	OLLAMA_PORT := os.Getenv("OLLAMA_PORT")
-	port, _ := strconv.Atoi(OLLAMA_PORT)
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	req, err := http.NewRequestWithContext(ctx, "GET",
-		fmt.Sprintf("http://%s:%d", ip, port), nil)
-	if err != nil {
-		return false
-	}
-
-	httpClient := &http.Client{}
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return false
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return false
-	}
-
-	buf := make([]byte, 1024)
-	n, err := resp.Body.Read(buf)
-	if err != nil && err != io.EOF {
-		return false
-	}
-
-	return strings.Contains(string(buf[:n]), "Ollama is running")
-}
-func getModels(ip string) []string {
-	OLLAMA_PORT := os.Getenv("OLLAMA_PORT")
-	port, _ := strconv.Atoi(OLLAMA_PORT)
-	httpClient := &http.Client{}
-	resp, err := httpClient.Get(fmt.Sprintf("http://%s:%d/api/tags", ip, port))
-	if err != nil || resp.StatusCode != http.StatusOK {
-		return nil
-	}
-	defer resp.Body.Close()
-
-	var data struct {
-		Models []struct {
-			Model string `json:"model"`
-		} `json:"models"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil
-	}
-
-	var models []string
-	for _, m := range data.Models {
-		if strings.Contains(m.Model, "deepseek-r1") {
-			models = append(models, m.Model)
-		}
-	}
-	return models
-}
-
-func parseModelSize(model string) float64 {
-	parts := strings.Split(model, ":")
-	if len(parts) < 2 {
-		return 0
-	}
-
-	sizeStr := strings.TrimSuffix(parts[len(parts)-1], "b")
-	size, err := strconv.ParseFloat(sizeStr, 64)
-	if err != nil {
-		return 0
-	}
-
-	return size
-}
-
-func sortModels(models []string) []string {
-	sort.Slice(models, func(i, j int) bool {
-		return parseModelSize(models[i]) < parseModelSize(models[j])
-	})
-	return models
-}
-
-func benchmarkModel(ip string, model string, benchPrompt string) (time.Duration, float64, string) {
-	disableBench := os.Getenv("disableBench")
-	if disableBench == "" {
-		if err := envmanager.UpdateEnvironmentVariable("disableBench", "false"); err != nil {
-			log.Printf("设置默认 disableBench 失败: %v", err)
-			return 0, 0, "系统配置错误"
-		}
-		disableBench = os.Getenv("disableBench")
-	}
-
-	if benchPrompt == "" {
-		if err := envmanager.UpdateEnvironmentVariable("benchPrompt", defaultBenchPrompt); err != nil {
-			log.Printf("设置默认 benchPrompt 失败: %v", err)
-			return 0, 0, "系统配置错误"
-		}
-		benchPrompt = os.Getenv("benchPrompt")
-	}
-	OLLAMA_PORT := os.Getenv("OLLAMA_PORT")
-	port, _ := strconv.Atoi(OLLAMA_PORT)
-	start := time.Now()
-	payload := map[string]interface{}{
-		"model":  model,
-		"prompt": benchPrompt,
-		"stream": true,
-	}
-
-	body, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST",
-		fmt.Sprintf("http://%s:%d/api/generate", ip, port),
-		bytes.NewReader(body))
-	httpClient := &http.Client{Timeout: benchTimeout}
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return 0, 0, "连接失败"
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return 0, 0, fmt.Sprintf("HTTP错误: %d", resp.StatusCode)
-	}
-
-	scanner := bufio.NewScanner(resp.Body)
-	var (
-		firstToken time.Time
-		lastToken  time.Time
-		tokenCount int
-	)
-
-	for scanner.Scan() {
-		if tokenCount == 0 {
-			firstToken = time.Now()
-		}
-
-		lastToken = time.Now()
-		tokenCount++
-		var data map[string]interface{}
-		if err := json.Unmarshal(scanner.Bytes(), &data); err != nil {
-			continue
-		}
-
-		if done, _ := data["done"].(bool); done {
-			break
-		}
-	}
-
-	if tokenCount == 0 {
-		return 0, 0, "无响应"
-	}
-
-	totalTime := lastToken.Sub(start)
-	return firstToken.Sub(start), float64(tokenCount) / totalTime.Seconds(), "完成"
-}
-
 // 添加默认值初始化函数
 func initDefaultValues() error {
 	defaults := map[string]string{
-		"OLLAMA_PORT":  "11434",
-		"disableBench": "false",
-		"masscanRate":  "1000",
-		"zmapThreads":  "10",
-		"benchPrompt":  "为什么太阳会发光？用一句话回答",
-		"OUTPUT_FILE":  "results.csv",
-		"INPUT_FILE":   "ip.txt",
-		"ENABLE_LOG":   "true",
-		"LOG_LEVEL":    "info",
+		"OLLAMA_PORT":          "11434",
+		"disableBench":         "false",
+		"masscanRate":          "1000",
+		"zmapThreads":          "10",
+		"benchPrompt":          "为什么太阳会发光？用一句话回答",
+		"OUTPUT_FILE":          "results.csv",
+		"INPUT_FILE":           "ip.txt",
+		"JSON_OUTPUT_FILE":     "results.json",
+		"ENABLE_MODEL_DETAILS": "false",
+		"BENCH_NUM_PREDICT":    "128",
+		"BENCH_TIMEOUT":        "30s",
+		"ENABLE_LOG":           "true",
+		"LOG_LEVEL":            "info",
+		"LOG_FORMAT":           "text",
 	}
 
 	for key, defaultValue := range defaults {
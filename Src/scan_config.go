@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aspnmy/ollama_scanner/bench"
+	"github.com/aspnmy/ollama_scanner/config"
+	"github.com/aspnmy/ollama_scanner/logging"
+	"github.com/aspnmy/ollama_scanner/policy"
+	"github.com/aspnmy/ollama_scanner/probe"
+	"github.com/aspnmy/ollama_scanner/scanner"
+	"github.com/aspnmy/ollama_scanner/scope"
+	"github.com/aspnmy/ollama_scanner/target"
+)
+
+// loadScanConfig 从环境变量构建扫描配置,并加载授权范围和自有端点列表.
+// overrides 在校验之前依次应用,用于守护进程任务和 API 请求中的覆盖项.
+// 所有配置问题在一次校验中收集,连同配置项名称和来源一起返回.
+func loadScanConfig(overrides ...func(*scanner.Config)) (scanner.Config, error) {
+	var v config.Validator
+
+	// MODEL_FILTER 未设置时沿用只扫描 deepseek-r1 的默认行为,设置为空表示保留全部模型
+	modelFilter, ok := os.LookupEnv("MODEL_FILTER")
+	if !ok {
+		modelFilter = scanner.DefaultModelFilter
+	}
+
+	cfg := scanner.Config{
+		Port:         v.Int("OLLAMA_PORT", defaultPort),
+		Timeout:      timeout,
+		Workers:      v.Int("MAX_WORKERS", maxWorkers),
+		ScannerType:  os.Getenv("scannerType"),
+		GatewayMAC:   os.Getenv("GATEWAY_MAC"),
+		ZmapThreads:  v.Int("zmapThreads", defaultZmapThreads),
+		MasscanRate:  v.Int("masscanRate", defaultMasscanRate),
+		InputFile:    os.Getenv("INPUT_FILE"),
+		ImportFile:   os.Getenv("IMPORT_FILE"),
+		ImportFormat: os.Getenv("IMPORT_FORMAT"),
+		ImportColumn: os.Getenv("IMPORT_COLUMN"),
+		ModelFilter:  modelFilter,
+		ModelDetails: config.GetEnvAsBool("ENABLE_MODEL_DETAILS", false),
+		DisableBench: config.GetEnvAsBool("disableBench", false),
+		Bench: bench.Config{
+			Prompt:         os.Getenv("benchPrompt"),
+			NumPredict:     v.Int("BENCH_NUM_PREDICT", bench.DefaultNumPredict),
+			Timeout:        v.Duration("BENCH_TIMEOUT", bench.DefaultTimeout),
+			MaxModelSizeGB: v.Float("BENCH_MAX_MODEL_SIZE_GB", 0),
+			WarmupRuns:     v.Int("BENCH_WARMUP_RUNS", 0),
+			Runs:           v.Int("BENCH_RUNS", bench.DefaultRuns),
+			ColdStart:      config.GetEnvAsBool("BENCH_COLD_START", false),
+			EmbedBatchSizes: intList(&v, "BENCH_EMBED_BATCH_SIZES", bench.DefaultEmbedBatchSizes,
+				1, bench.MaxEmbedBatchSize),
+			Mode: os.Getenv("BENCH_MODE"),
+		},
+		RateLimit: probe.LimitConfig{
+			Rate:        v.Int("HTTP_RATE_LIMIT", 0),
+			Burst:       v.Int("HTTP_BURST_LIMIT", 0),
+			SubnetRate:  v.Int("HTTP_SUBNET_RATE_LIMIT", probe.DefaultSubnetRateLimit),
+			SubnetBurst: v.Int("HTTP_SUBNET_BURST_LIMIT", probe.DefaultSubnetBurstLimit),
+		},
+		HTTP: probe.ClientConfig{
+			MaxIdleConns:          v.Int("HTTP_MAX_IDLE_CONNS", maxIdleConns),
+			MaxIdleConnsPerHost:   v.Int("HTTP_MAX_IDLE_CONNS_PER_HOST", probe.DefaultMaxIdleConnsPerHost),
+			MaxConnsPerHost:       v.Int("HTTP_MAX_CONNS_PER_HOST", probe.DefaultMaxConnsPerHost),
+			IdleConnTimeout:       v.Duration("HTTP_IDLE_TIMEOUT", idleConnTimeout),
+			DialTimeout:           v.Duration("HTTP_DIAL_TIMEOUT", timeout),
+			TLSHandshakeTimeout:   v.Duration("HTTP_TLS_TIMEOUT", probe.DefaultTLSHandshakeTimeout),
+			ResponseHeaderTimeout: v.Duration("HTTP_RESPONSE_HEADER_TIMEOUT", probe.DefaultResponseHeaderTimeout),
+			MaxResponseSize:       int64(v.Int("HTTP_MAX_RESPONSE_SIZE", probe.DefaultMaxResponseSize)),
+		},
+		Logger:   slog.Default(),
+		Location: config.Location(),
+	}
+
+	shard, err := scanner.ParseShard(os.Getenv("SHARD"))
+	if err != nil {
+		v.Errorf("SHARD", "%v", err)
+	}
+	cfg.Shard = shard
+	cfg.Scope = loadScope(&v)
+	cfg.Bench.Owned = loadTargetList(&v, "BENCH_OWNED_FILE", false)
+	if path := os.Getenv("POLICY_FILE"); path != "" {
+		cfg.Policy, err = policy.Load(path)
+		if err != nil {
+			v.Errorf("POLICY_FILE", "%v", err)
+		}
+	}
+	loadConversation(&v, &cfg.Bench)
+	if path := os.Getenv("BENCH_SMOKE_FILE"); path != "" {
+		suite, err := bench.LoadSmokeTests(path)
+		if err != nil {
+			v.Errorf("BENCH_SMOKE_FILE", "%v", err)
+		}
+		cfg.Bench.Smoke = suite
+	}
+	for _, override := range overrides {
+		override(&cfg)
+	}
+	validateScanConfig(&v, cfg)
+
+	if err := v.Err(); err != nil {
+		return scanner.Config{}, err
+	}
+	return cfg, nil
+}
+
+// loadConversation 校验性能测试模式,并在对话模式下读取 BENCH_CHAT_FILE 中的对话脚本
+func loadConversation(v *config.Validator, cfg *bench.Config) {
+	path := os.Getenv("BENCH_CHAT_FILE")
+	switch cfg.Mode {
+	case "", bench.ModeGenerate:
+		if path != "" {
+			v.Errorf("BENCH_CHAT_FILE", "只在 BENCH_MODE=chat 时使用")
+		}
+		return
+	case bench.ModeChat:
+	default:
+		v.Errorf("BENCH_MODE", "必须是 generate 或 chat: %q", cfg.Mode)
+		return
+	}
+	if path == "" {
+		return
+	}
+	conv, err := bench.LoadConversation(path)
+	if err != nil {
+		v.Errorf("BENCH_CHAT_FILE", "%v", err)
+		return
+	}
+	cfg.Conversation = conv
+}
+
+// intList 读取逗号分隔的整数列表,每个值必须在 [lo, hi] 之间,未设置时返回默认值
+func intList(v *config.Validator, key string, defaultVal []int, lo int, hi int) []int {
+	value := os.Getenv(key)
+	if strings.TrimSpace(value) == "" {
+		return defaultVal
+	}
+	var list []int
+	for _, field := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < lo || n > hi {
+			v.Errorf(key, "必须是 %d 到 %d 之间的整数,以逗号分隔: %q", lo, hi, value)
+			return defaultVal
+		}
+		list = append(list, n)
+	}
+	return list
+}
+
+// loadScope 分别加载 SCOPE_FILE 和 DENYLIST_FILE,以便把问题归到对应的配置项
+func loadScope(v *config.Validator) *scope.Scope {
+	sc, err := scope.Load(os.Getenv("SCOPE_FILE"), "")
+	if err != nil {
+		v.Errorf("SCOPE_FILE", "%v", err)
+	}
+	deny := loadTargetList(v, "DENYLIST_FILE", false)
+	if sc != nil {
+		sc.AddDeny(deny...)
+	}
+	return sc
+}
+
+// loadTargetList 读取配置项指向的目标文件,未设置时只有 required 为真才算错误
+func loadTargetList(v *config.Validator, key string, required bool) []netip.Prefix {
+	path := os.Getenv(key)
+	if path == "" {
+		if required {
+			v.Errorf(key, "未设置")
+		}
+		return nil
+	}
+	prefixes, err := scope.ReadTargetFile(path)
+	if err != nil {
+		v.Errorf(key, "%v", err)
+	}
+	return prefixes
+}
+
+// inputResolveTimeout 为校验输入文件时解析主机名的总时间上限
+const inputResolveTimeout = 30 * time.Second
+
+// checkInputFile 在启动前解析输入文件,报告格式错误和无法解析的主机名
+func checkInputFile(v *config.Validator, path string) {
+	if path == "" {
+		v.Errorf("INPUT_FILE", "未设置")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), inputResolveTimeout)
+	defer cancel()
+	targets, err := target.ReadFile(ctx, path)
+	if err != nil {
+		v.Errorf("INPUT_FILE", "%v", err)
+		return
+	}
+	if len(targets) == 0 {
+		v.Errorf("INPUT_FILE", "%s 中没有任何目标", path)
+	}
+}
+
+// checkImportFile 在启动前解析导入清单,设置了 IMPORT_FILE 时不再要求 INPUT_FILE
+func checkImportFile(v *config.Validator, cfg scanner.Config) {
+	switch cfg.ImportFormat {
+	case "", target.FormatNmap, target.FormatCSV, target.FormatJSON:
+	default:
+		v.Errorf("IMPORT_FORMAT", "不支持的导入格式: %q,可选 nmap、csv 或 json", cfg.ImportFormat)
+		return
+	}
+	if cfg.ImportFormat == "" && target.DetectFormat(cfg.ImportFile) == "" {
+		v.Errorf("IMPORT_FORMAT", "无法根据扩展名判断 %s 的格式,请设置 nmap、csv 或 json", cfg.ImportFile)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), inputResolveTimeout)
+	defer cancel()
+	targets, err := target.Import(ctx, cfg.ImportFile, cfg.ImportFormat, cfg.ImportColumn, cfg.Port)
+	if err != nil {
+		v.Errorf("IMPORT_FILE", "%v", err)
+		return
+	}
+	if len(targets) == 0 {
+		v.Errorf("IMPORT_FILE", "%s 中没有任何目标", cfg.ImportFile)
+	}
+}
+
+// validateScanConfig 检查已解析配置的取值范围,问题记录到 v 中
+func validateScanConfig(v *config.Validator, cfg scanner.Config) {
+	if _, err := net.ParseMAC(strings.Trim(cfg.GatewayMAC, `'"`)); err != nil {
+		v.Errorf("GATEWAY_MAC", "MAC 地址格式无效: %q", cfg.GatewayMAC)
+	}
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		v.Errorf("OLLAMA_PORT", "端口必须在 1-65535 之间: %d", cfg.Port)
+	}
+	switch cfg.ScannerType {
+	case "", "zmap", "masscan", "native":
+	default:
+		v.Errorf("scannerType", "不支持的扫描器类型: %q,可选 zmap、masscan 或 native", cfg.ScannerType)
+	}
+	if cfg.ImportFile != "" {
+		checkImportFile(v, cfg)
+	} else {
+		checkInputFile(v, cfg.InputFile)
+	}
+
+	positive := []struct {
+		key   string
+		value int
+	}{
+		{"MAX_WORKERS", cfg.Workers},
+		{"zmapThreads", cfg.ZmapThreads},
+		{"masscanRate", cfg.MasscanRate},
+		{"BENCH_NUM_PREDICT", cfg.Bench.NumPredict},
+		{"BENCH_RUNS", cfg.Bench.Runs},
+	}
+	for _, p := range positive {
+		if p.value <= 0 {
+			v.Errorf(p.key, "必须为正整数: %d", p.value)
+		}
+	}
+	nonNegative := []struct {
+		key   string
+		value int
+	}{
+		{"HTTP_RATE_LIMIT", cfg.RateLimit.Rate},
+		{"HTTP_BURST_LIMIT", cfg.RateLimit.Burst},
+		{"HTTP_SUBNET_RATE_LIMIT", cfg.RateLimit.SubnetRate},
+		{"HTTP_SUBNET_BURST_LIMIT", cfg.RateLimit.SubnetBurst},
+		{"HTTP_MAX_IDLE_CONNS", cfg.HTTP.MaxIdleConns},
+		{"HTTP_MAX_IDLE_CONNS_PER_HOST", cfg.HTTP.MaxIdleConnsPerHost},
+		{"HTTP_MAX_CONNS_PER_HOST", cfg.HTTP.MaxConnsPerHost},
+		{"HTTP_MAX_RESPONSE_SIZE", int(cfg.HTTP.MaxResponseSize)},
+		{"BENCH_WARMUP_RUNS", cfg.Bench.WarmupRuns},
+	}
+	for _, n := range nonNegative {
+		if n.value < 0 {
+			v.Errorf(n.key, "不能为负数: %d", n.value)
+		}
+	}
+	if cfg.Bench.MaxModelSizeGB < 0 {
+		v.Errorf("BENCH_MAX_MODEL_SIZE_GB", "不能为负数: %g", cfg.Bench.MaxModelSizeGB)
+	}
+
+	if !cfg.DisableBench {
+		if !utf8.ValidString(cfg.Bench.Prompt) {
+			v.Errorf("benchPrompt", "不是有效的 UTF-8 文本")
+		} else if strings.TrimSpace(cfg.Bench.Prompt) == "" {
+			v.Errorf("benchPrompt", "启用性能测试时提示词不能为空")
+		}
+	}
+
+	for _, key := range []string{"OUTPUT_FILE", "JSON_OUTPUT_FILE", "STATE_FILE", "BENCH_SAMPLES_FILE"} {
+		if path := os.Getenv(key); path != "" {
+			if err := checkWritableDir(filepath.Dir(path)); err != nil {
+				v.Errorf(key, "%v", err)
+			}
+		}
+	}
+
+	if tz := os.Getenv("TIMEZONE"); tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			v.Errorf("TIMEZONE", "无法识别的时区: %q", tz)
+		}
+	}
+	if _, err := logging.ParseLevel(os.Getenv("LOG_LEVEL")); err != nil {
+		v.Errorf("LOG_LEVEL", "%v", err)
+	}
+}
+
+// checkWritableDir 通过创建临时文件确认目录存在且可写
+func checkWritableDir(dir string) error {
+	f, err := os.CreateTemp(dir, ".ollama_scanner_check_*")
+	if err != nil {
+		return fmt.Errorf("输出目录 %s 不可写: %w", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
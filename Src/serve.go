@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/api"
+	"github.com/aspnmy/ollama_scanner/config"
+	"github.com/aspnmy/ollama_scanner/daemon"
+	"github.com/aspnmy/ollama_scanner/scanner"
+	"github.com/aspnmy/ollama_scanner/store"
+	"github.com/aspnmy/ollama_scanner/web"
+)
+
+const defaultHTTPPort = "8080"
+
+// runServe 实现 serve 子命令:提供提交扫描和查询结果的 REST API,
+// 并在根路径提供嵌入的结果浏览页面.返回值为进程退出码.
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:"+envOrDefault("HTTP_PORT", defaultHTTPPort), "监听地址,未设置 API_TOKEN 时只能监听本机回环地址")
+	storeDir := fs.String("store", envOrDefault("STORE_DIR", daemon.DefaultStoreDir), "结果存储目录,与 daemon 共用时应指向同一目录")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	logCloser := setupLogger()
+	defer logCloser.Close()
+	if err := config.InitTimeZone(os.Getenv("TIMEZONE")); err != nil {
+		slog.Warn("初始化时区失败", "error", err)
+	}
+	// 启动前确认扫描配置可用,之后每次提交扫描时重新读取
+	if _, err := prepareScanConfig(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	st, err := store.Open(*storeDir)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	defer st.Close()
+
+	token := os.Getenv("API_TOKEN")
+	if token == "" {
+		if !isLoopbackAddr(*addr) {
+			fmt.Printf("❌ 未设置 API_TOKEN 时不能监听 %s，请设置 API_TOKEN 或改为监听 127.0.0.1\n", *addr)
+			return 1
+		}
+		slog.Warn("未设置 API_TOKEN，API 不要求认证", "addr", *addr)
+		fmt.Println("⚠️ 未设置 API_TOKEN，本机上的任何用户都可以提交扫描")
+	}
+	apiServer := api.New(api.Config{
+		Store:      st,
+		NewScanner: newAPIScanner,
+		Token:      token,
+		MaxScans:   config.GetEnvAsInt("API_MAX_SCANS", api.DefaultMaxScans),
+		Logger:     slog.Default(),
+		Location:   config.Location(),
+	})
+	apiServer.Handle("GET /", web.Handler())
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           apiServer,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		fmt.Println("\n⚠️ 收到终止信号，正在停止 API 服务...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("🌐 API 服务已启动: %s，结果保存在 %s\n", *addr, *storeDir)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("❌ API 服务异常退出: %v\n", err)
+		return 1
+	}
+	// 取消进行中的扫描,已产生的结果和运行记录写入结果存储后再退出
+	apiServer.Close()
+	return 0
+}
+
+// isLoopbackAddr 判断监听地址是否只在本机回环接口上,主机部分为空时监听所有接口
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.IsLoopback()
+}
+
+// newAPIScanner 以环境变量中的扫描配置为基础,应用请求中的覆盖项并校验后构建扫描器
+func newAPIScanner(req api.ScanRequest) (*scanner.Scanner, error) {
+	cfg, err := loadScanConfig(func(cfg *scanner.Config) {
+		// 请求中的目标即为全部扫描对象,不再导入清单,也不按 SHARD 只扫描其中一部分
+		cfg.InputFile = req.InputFile
+		cfg.ImportFile = ""
+		cfg.Shard = scanner.Shard{}
+		if req.ModelFilter != nil {
+			cfg.ModelFilter = *req.ModelFilter
+		}
+		if req.DisableBench != nil {
+			cfg.DisableBench = *req.DisableBench
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scanner.New(cfg), nil
+}
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/scanner"
+	"github.com/aspnmy/ollama_scanner/scope"
+	"github.com/aspnmy/ollama_scanner/sink"
+	"github.com/aspnmy/ollama_scanner/store"
+)
+
+const (
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
+)
+
+// HostRecord 为一个主机在某次运行中的扫描结果
+type HostRecord struct {
+	RunID string `json:"run_id"`
+	Job   string `json:"job"`
+	Risk  Risk   `json:"risk"`
+	scanner.ScanResult
+}
+
+// HostDetail 为单个主机最近一次的结果以及全部历史结果
+type HostDetail struct {
+	HostRecord
+	// History 包含最近一次结果在内,按时间从新到旧排列
+	History []HostRecord `json:"history"`
+}
+
+// ModelRecord 为某个主机上的单个模型
+type ModelRecord struct {
+	IP       string    `json:"ip"`
+	Port     int       `json:"port,omitempty"`
+	RunID    string    `json:"run_id"`
+	Job      string    `json:"job"`
+	ProbedAt time.Time `json:"probed_at"`
+	scanner.ModelInfo
+}
+
+// filter 为主机和模型查询共用的过滤条件
+type filter struct {
+	network *netip.Prefix
+	model   string
+	job     string
+	run     string
+	since   time.Time
+	status  string
+	minTPS  float64
+	limit   int
+}
+
+// parseFilter 解析查询参数:ip(IP 或网段)、model(名称子串)、job、run、
+// since(RFC 3339)、status、min_tps 和 limit
+func parseFilter(q url.Values) (filter, error) {
+	f := filter{
+		model:  q.Get("model"),
+		job:    q.Get("job"),
+		run:    q.Get("run"),
+		status: q.Get("status"),
+		limit:  defaultQueryLimit,
+	}
+	if v := q.Get("ip"); v != "" {
+		p, err := scope.ParseTarget(v)
+		if err != nil {
+			return f, err
+		}
+		f.network = &p
+	}
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, fmt.Errorf("since 应为 RFC 3339 时间: %q", v)
+		}
+		f.since = t
+	}
+	if v := q.Get("min_tps"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return f, fmt.Errorf("min_tps 应为数字: %q", v)
+		}
+		f.minTPS = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return f, fmt.Errorf("limit 应为正整数: %q", v)
+		}
+		f.limit = min(n, maxQueryLimit)
+	}
+	return f, nil
+}
+
+func (f filter) matchRun(r store.Run) bool {
+	return (f.job == "" || r.Job == f.job) && (f.run == "" || r.ID == f.run)
+}
+
+func (f filter) matchHost(res scanner.ScanResult) bool {
+	if f.network != nil && !scope.Match([]netip.Prefix{*f.network}, res.IP) {
+		return false
+	}
+	if !f.since.IsZero() && res.ProbedAt.Before(f.since) {
+		return false
+	}
+	// 指定了模型条件时,主机上至少有一个模型满足条件
+	if f.model != "" || f.status != "" || f.minTPS > 0 {
+		for _, m := range res.Models {
+			if f.matchModel(m) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (f filter) matchModel(m scanner.ModelInfo) bool {
+	return (f.model == "" || strings.Contains(m.Name, f.model)) &&
+		(f.status == "" || m.Status == f.status) &&
+		m.TokensPerSec >= f.minTPS
+}
+
+// latestHosts 按运行记录从新到旧查找,每个地址只保留最近一次结果,结果按地址排序
+func (s *Server) latestHosts(f filter) ([]HostRecord, error) {
+	seen := map[string]bool{}
+	var hosts []HostRecord
+	for _, run := range s.cfg.Store.Runs("") {
+		if run.Status == store.RunSkipped || !f.matchRun(run) {
+			continue
+		}
+		results, err := s.cfg.Store.Results(run.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, res := range results {
+			if seen[res.Address()] {
+				continue
+			}
+			seen[res.Address()] = true
+			if f.matchHost(res) {
+				hosts = append(hosts, HostRecord{RunID: run.ID, Job: run.Job, Risk: RiskScore(res), ScanResult: res})
+			}
+		}
+	}
+	slices.SortFunc(hosts, func(a, b HostRecord) int { return scanner.CompareAddress(a.ScanResult, b.ScanResult) })
+	return hosts, nil
+}
+
+func (s *Server) listHosts(w http.ResponseWriter, r *http.Request) {
+	f, err := parseFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	hosts, err := s.latestHosts(f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(hosts) > f.limit {
+		hosts = hosts[:f.limit]
+	}
+	if hosts == nil {
+		hosts = []HostRecord{}
+	}
+	writeJSON(w, http.StatusOK, hosts)
+}
+
+// getHost 返回单个主机最近一次的结果、风险评分和历史结果,
+// 路径中的地址为 IP,或指定了端口的主机的 IP:端口
+func (s *Server) getHost(w http.ResponseWriter, r *http.Request) {
+	ip := r.PathValue("ip")
+	var history []HostRecord
+	for _, run := range s.cfg.Store.Runs("") {
+		if run.Status == store.RunSkipped {
+			continue
+		}
+		results, err := s.cfg.Store.Results(run.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		for _, res := range results {
+			if res.Address() == ip {
+				history = append(history, HostRecord{RunID: run.ID, Job: run.Job, Risk: RiskScore(res), ScanResult: res})
+			}
+		}
+	}
+	if len(history) == 0 {
+		writeError(w, http.StatusNotFound, "没有该主机的扫描结果")
+		return
+	}
+	writeJSON(w, http.StatusOK, HostDetail{HostRecord: history[0], History: history})
+}
+
+// export 以 CSV 或 JSON Lines 下载符合条件的主机的最近一次结果
+func (s *Server) export(w http.ResponseWriter, r *http.Request) {
+	f, err := parseFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// 导出不受默认条数限制
+	if r.URL.Query().Get("limit") == "" {
+		f.limit = 0
+	}
+	hosts, err := s.latestHosts(f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if f.limit > 0 && len(hosts) > f.limit {
+		hosts = hosts[:f.limit]
+	}
+
+	name := "ollama_hosts_" + s.now().Format("20060102T150405")
+	var out sink.Sink
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".csv"))
+		// 写入 BOM,便于 Excel 正确识别中文
+		w.Write([]byte("\ufeff"))
+		c, err := sink.NewCSVWriter(w, sink.Options{Bench: true, BenchStats: true, Details: true, Smoke: true, Policy: true})
+		if err != nil {
+			s.log.Warn("导出CSV失败", "error", err)
+			return
+		}
+		out = c
+	case "json":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".jsonl"))
+		out = sink.NewJSONWriter(w)
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("不支持的导出格式: %q,可选 csv 或 json", format))
+		return
+	}
+	for _, h := range hosts {
+		if err := out.Write(h.ScanResult); err != nil {
+			s.log.Warn("导出结果失败", "error", err)
+			break
+		}
+	}
+	out.Close()
+}
+
+func (s *Server) listModels(w http.ResponseWriter, r *http.Request) {
+	f, err := parseFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	hosts, err := s.latestHosts(f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	models := []ModelRecord{}
+	for _, h := range hosts {
+		for _, m := range h.Models {
+			if len(models) == f.limit {
+				break
+			}
+			if f.matchModel(m) {
+				models = append(models, ModelRecord{IP: h.IP, Port: h.Port, RunID: h.RunID, Job: h.Job, ProbedAt: h.ProbedAt, ModelInfo: m})
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, models)
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aspnmy/ollama_scanner/scanner"
+)
+
+// Risk 为单个主机的风险评分,分数越高越需要优先处置
+type Risk struct {
+	Score   int      `json:"score"`
+	Level   string   `json:"level"`
+	Reasons []string `json:"reasons"`
+}
+
+// patchedVersion 为修复 CVE-2024-37032(/api/pull 路径穿越)的版本
+const patchedVersion = "0.1.34"
+
+// RiskScore 根据暴露面、可用算力和版本估算主机的风险
+func RiskScore(res scanner.ScanResult) Risk {
+	r := Risk{Score: 30, Reasons: []string{"Ollama API 无需认证即可访问"}}
+
+	benchmarked := 0
+	for _, m := range res.Models {
+		if m.Status == "完成" {
+			benchmarked++
+		}
+	}
+	if benchmarked > 0 {
+		r.Score += 20
+		r.Reasons = append(r.Reasons, fmt.Sprintf("%d 个模型可被直接调用生成内容", benchmarked))
+	}
+	if len(res.Running) > 0 {
+		r.Score += 10
+		r.Reasons = append(r.Reasons, fmt.Sprintf("%d 个模型已加载到内存", len(res.Running)))
+	}
+	if n := len(res.Models); n > 0 {
+		r.Score += min(n*2, 20)
+		r.Reasons = append(r.Reasons, fmt.Sprintf("暴露 %d 个模型", n))
+	}
+	if res.Version != "" && compareVersions(res.Version, patchedVersion) < 0 {
+		r.Score += 20
+		r.Reasons = append(r.Reasons, fmt.Sprintf("版本 %s 低于 %s,受 CVE-2024-37032 影响", res.Version, patchedVersion))
+	}
+
+	r.Score = min(r.Score, 100)
+	switch {
+	case r.Score >= 70:
+		r.Level = "高"
+	case r.Score >= 40:
+		r.Level = "中"
+	default:
+		r.Level = "低"
+	}
+	return r
+}
+
+// compareVersions 按数字逐段比较版本号,忽略 v 前缀和预发布后缀
+func compareVersions(a string, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := range max(len(pa), len(pb)) {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "-")
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(s)
+		parts = append(parts, n)
+	}
+	return parts
+}
@@ -0,0 +1,360 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/scanner"
+	"github.com/aspnmy/ollama_scanner/scope"
+	"github.com/aspnmy/ollama_scanner/store"
+	"github.com/aspnmy/ollama_scanner/target"
+)
+
+// apiJobName 为通过 API 提交的扫描在运行记录中的任务名称
+const apiJobName = "api"
+
+// Progress 为扫描进度
+type Progress struct {
+	Discovered int `json:"discovered"`
+	Completed  int `json:"completed"`
+	Pending    int `json:"pending"`
+}
+
+// ScanStatus 为扫描任务的状态和进度
+type ScanStatus struct {
+	store.Run
+	Progress *Progress `json:"progress,omitempty"`
+}
+
+// job 为由本服务启动、仍保存在内存中的扫描任务
+type job struct {
+	scanner *scanner.Scanner
+	cancel  func()
+
+	mu      sync.Mutex
+	results []scanner.ScanResult
+	done    bool
+	// changed 在每次有新结果或任务结束时关闭并替换,用于通知事件流
+	changed chan struct{}
+}
+
+func (j *job) add(res scanner.ScanResult) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.results = append(j.results, res)
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+func (j *job) finish() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.done = true
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// since 返回第 n 条之后的结果、任务是否结束以及下一次变化的通知通道
+func (j *job) since(n int) ([]scanner.ScanResult, bool, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.results[n:], j.done, j.changed
+}
+
+func (j *job) progress() *Progress {
+	st := j.scanner.State()
+	return &Progress{Discovered: len(st.Discovered), Completed: len(st.Completed), Pending: len(st.Pending)}
+}
+
+func (s *Server) job(id string) *job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs[id]
+}
+
+// runningJobs 返回进行中的任务数量
+func (s *Server) runningJobs() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.jobs)
+}
+
+// submitScan 校验目标后启动扫描,目标不在授权范围内时返回 403
+func (s *Server) submitScan(w http.ResponseWriter, r *http.Request) {
+	var req ScanRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("请求体无效: %v", err))
+		return
+	}
+	if len(req.Targets) == 0 {
+		writeError(w, http.StatusBadRequest, "targets 不能为空")
+		return
+	}
+	// 格式与输入文件相同,错误中的行号为目标在 targets 中的序号
+	if _, err := target.Read(r.Context(), strings.NewReader(strings.Join(req.Targets, "\n")), "targets"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// 串行处理提交,保证并发数检查和启动之间不会插入其他扫描
+	s.submitMu.Lock()
+	defer s.submitMu.Unlock()
+	if s.runningJobs() >= s.cfg.MaxScans {
+		writeError(w, http.StatusTooManyRequests, fmt.Sprintf("已有 %d 个扫描在运行，请稍后再试", s.cfg.MaxScans))
+		return
+	}
+
+	input, err := writeTargets(req.Targets)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	req.InputFile = input
+	status, err := s.startScan(req)
+	if err != nil {
+		os.Remove(input)
+		code := http.StatusInternalServerError
+		if errors.Is(err, scope.ErrOutOfScope) {
+			code = http.StatusForbidden
+		}
+		writeError(w, code, err.Error())
+		return
+	}
+	w.Header().Set("Location", "/api/scans/"+status.ID)
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+// writeTargets 将目标写入临时输入文件
+func writeTargets(targets []string) (string, error) {
+	f, err := os.CreateTemp("", "ollama_scanner_api_*.txt")
+	if err != nil {
+		return "", fmt.Errorf("创建输入文件失败: %w", err)
+	}
+	defer f.Close()
+	for _, t := range targets {
+		if _, err := fmt.Fprintln(f, strings.TrimSpace(t)); err != nil {
+			os.Remove(f.Name())
+			return "", fmt.Errorf("写入输入文件失败: %w", err)
+		}
+	}
+	return f.Name(), nil
+}
+
+// startScan 启动扫描并在后台把结果写入结果存储
+func (s *Server) startScan(req ScanRequest) (ScanStatus, error) {
+	sc, err := s.cfg.NewScanner(req)
+	if err != nil {
+		return ScanStatus{}, err
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	results, err := sc.Run(ctx)
+	if err != nil {
+		cancel()
+		return ScanStatus{}, err
+	}
+	rec, err := s.cfg.Store.Begin(apiJobName, s.now())
+	if err != nil {
+		cancel()
+		for range results {
+		}
+		return ScanStatus{}, err
+	}
+
+	j := &job{scanner: sc, cancel: cancel, changed: make(chan struct{})}
+	s.mu.Lock()
+	s.jobs[rec.ID()] = j
+	s.mu.Unlock()
+	s.log.Info("通过 API 启动扫描", "run", rec.ID(), "targets", req.Targets)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer os.Remove(req.InputFile)
+		defer cancel()
+		for res := range results {
+			if err := rec.Write(res); err != nil {
+				s.log.Warn("写入结果失败", "run", rec.ID(), "error", err)
+			}
+			j.add(res)
+		}
+		err := sc.Err()
+		if cerr := rec.Close(); cerr != nil {
+			err = errors.Join(err, cerr)
+		}
+		status := store.RunSucceeded
+		switch {
+		case ctx.Err() != nil:
+			status = store.RunCancelled
+		case err != nil:
+			status = store.RunFailed
+		}
+		if ferr := rec.Finish(status, err, s.now()); ferr != nil {
+			s.log.Error("更新运行记录失败", "run", rec.ID(), "error", ferr)
+		}
+		j.finish()
+		// 结果已全部写入结果存储,之后的查询直接读取存储
+		s.mu.Lock()
+		delete(s.jobs, rec.ID())
+		s.mu.Unlock()
+		s.log.Info("API 扫描结束", "run", rec.ID(), "status", status)
+	}()
+
+	run, _ := s.cfg.Store.Run(rec.ID())
+	return ScanStatus{Run: run, Progress: j.progress()}, nil
+}
+
+func (s *Server) listScans(w http.ResponseWriter, r *http.Request) {
+	runs := s.cfg.Store.Runs(r.URL.Query().Get("job"))
+	if runs == nil {
+		runs = []store.Run{}
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// scanStatus 返回运行记录,由本服务启动且仍在内存中的任务附带进度
+func (s *Server) scanStatus(id string) (ScanStatus, bool) {
+	run, ok := s.cfg.Store.Run(id)
+	if !ok {
+		return ScanStatus{}, false
+	}
+	status := ScanStatus{Run: run}
+	if j := s.job(id); j != nil {
+		status.Progress = j.progress()
+	}
+	return status, true
+}
+
+func (s *Server) getScan(w http.ResponseWriter, r *http.Request) {
+	status, ok := s.scanStatus(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "扫描任务不存在")
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// cancelScan 取消由本服务启动的扫描,已产生的结果仍会保存
+func (s *Server) cancelScan(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	j := s.job(id)
+	if j == nil {
+		writeError(w, http.StatusNotFound, "扫描任务不存在或不是由本服务启动")
+		return
+	}
+	j.cancel()
+	status, _ := s.scanStatus(id)
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+func (s *Server) scanResults(w http.ResponseWriter, r *http.Request) {
+	results, err := s.cfg.Store.Results(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if results == nil {
+		results = []scanner.ScanResult{}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// progressInterval 为事件流中推送进度的间隔
+const progressInterval = time.Second
+
+// scanEvents 以 server-sent events 推送扫描结果:
+// result 事件为单个主机结果,progress 事件为进度,done 事件为最终的运行记录.
+// 已结束的任务先回放全部结果再发送 done.其他进程(例如守护进程)中进行中的任务
+// 没有进度,改为定期读取结果存储,直到运行记录进入结束状态.
+func (s *Server) scanEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	status, ok := s.scanStatus(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "扫描任务不存在")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "连接不支持事件流")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	j := s.job(id)
+	if j == nil {
+		s.storedEvents(w, r, flusher, status.Run)
+		return
+	}
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	sent := 0
+	for {
+		results, done, changed := j.since(sent)
+		for _, res := range results {
+			writeEvent(w, "result", res)
+		}
+		sent += len(results)
+		if done {
+			final, _ := s.scanStatus(id)
+			writeEvent(w, "done", final)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+		case <-ticker.C:
+			writeEvent(w, "progress", j.progress())
+		}
+	}
+}
+
+// storedEvents 推送结果存储中的结果,运行记录仍为 running 时每隔 progressInterval 重新读取
+func (s *Server) storedEvents(w http.ResponseWriter, r *http.Request, flusher http.Flusher, run store.Run) {
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	sent := 0
+	for {
+		// 先读取运行记录再读取结果,记录已结束时结果文件已经写完
+		terminal := run.Status != store.RunRunning
+		results, _ := s.cfg.Store.Results(run.ID)
+		if len(results) > sent {
+			for _, res := range results[sent:] {
+				writeEvent(w, "result", res)
+			}
+			sent = len(results)
+		}
+		if terminal {
+			writeEvent(w, "done", ScanStatus{Run: run})
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		run, _ = s.cfg.Store.Run(run.ID)
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event string, v any) {
+	data, _ := json.Marshal(v)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
@@ -0,0 +1,108 @@
+package api_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/api"
+	"github.com/aspnmy/ollama_scanner/scanner"
+	"github.com/aspnmy/ollama_scanner/store"
+)
+
+type event struct {
+	name string
+	data string
+}
+
+// readEvents 读取事件流直到 done 事件或连接关闭
+func readEvents(t *testing.T, url string) []event {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("状态码 %d", resp.StatusCode)
+	}
+	var events []event
+	var cur event
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			cur.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			cur.data = strings.TrimPrefix(line, "data: ")
+		case line == "":
+			events = append(events, cur)
+			if cur.name == "done" {
+				return events
+			}
+			cur = event{}
+		}
+	}
+	return events
+}
+
+func TestScanEventsStoredRun(t *testing.T) {
+	st, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	srv := httptest.NewServer(api.New(api.Config{Store: st}))
+	defer srv.Close()
+
+	// 模拟守护进程写入的运行记录:事件流开始时仍在运行,之后写入结果并结束
+	rec, err := st.Begin("nightly", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Write(scanner.ScanResult{IP: "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		rec.Write(scanner.ScanResult{IP: "10.0.0.2"})
+		rec.Close()
+		rec.Finish(store.RunSucceeded, nil, time.Now())
+	}()
+
+	events := readEvents(t, srv.URL+"/api/scans/"+rec.ID()+"/events")
+	var names []string
+	for _, e := range events {
+		names = append(names, e.name)
+	}
+	if got := strings.Join(names, ","); got != "result,result,done" {
+		t.Fatalf("事件 = %s, 期望 result,result,done", got)
+	}
+	var done api.ScanStatus
+	if err := json.Unmarshal([]byte(events[2].data), &done); err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != store.RunSucceeded || done.Hosts != 2 {
+		t.Fatalf("done = %+v", done)
+	}
+
+	// 已结束的运行直接回放
+	events = readEvents(t, srv.URL+"/api/scans/"+rec.ID()+"/events")
+	if len(events) != 3 || events[2].name != "done" {
+		t.Fatalf("回放事件 = %+v", events)
+	}
+
+	resp, err := http.Get(srv.URL + "/api/scans/missing/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("不存在的任务状态码 = %d, 期望 404", resp.StatusCode)
+	}
+}
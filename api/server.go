@@ -0,0 +1,151 @@
+// Package api 提供扫描任务的 REST 接口:提交扫描、查询任务状态和进度、
+// 以 server-sent events 推送结果,以及按条件查询已保存的主机和模型.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/logging"
+	"github.com/aspnmy/ollama_scanner/scanner"
+	"github.com/aspnmy/ollama_scanner/store"
+)
+
+const (
+	DefaultMaxScans = 1
+	// maxRequestBody 为提交扫描请求体的大小上限
+	maxRequestBody = 1 << 20
+)
+
+// ScanRequest 为提交扫描的请求体,未设置的字段沿用服务端的扫描配置
+type ScanRequest struct {
+	// Targets 为 IP 或 CIDR 网段,必须全部落在授权范围内
+	Targets      []string `json:"targets"`
+	ModelFilter  *string  `json:"model_filter,omitempty"`
+	DisableBench *bool    `json:"disable_bench,omitempty"`
+	// InputFile 为服务端写入目标的临时文件,由 Server 填写
+	InputFile string `json:"-"`
+}
+
+// Config 为 API 服务配置
+type Config struct {
+	Store *store.Store
+	// NewScanner 根据请求构建扫描器,授权范围检查在 Scanner.Run 中完成
+	NewScanner func(req ScanRequest) (*scanner.Scanner, error)
+	// Token 非空时要求请求携带 Authorization: Bearer <Token>
+	Token string
+	// MaxScans 为同时运行的扫描数量上限
+	MaxScans int
+	// Logger 为空时不输出日志
+	Logger   *slog.Logger
+	Location *time.Location
+}
+
+// Server 为 REST API 服务,记录由它启动的扫描任务
+type Server struct {
+	cfg Config
+	log *slog.Logger
+	mux *http.ServeMux
+
+	// ctx 在 Close 时取消,用于停止所有进行中的扫描
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	submitMu sync.Mutex
+	mu       sync.Mutex
+	jobs     map[string]*job
+}
+
+// New 创建 API 服务
+func New(cfg Config) *Server {
+	if cfg.MaxScans <= 0 {
+		cfg.MaxScans = DefaultMaxScans
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logging.Discard()
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		cfg:    cfg,
+		log:    cfg.Logger,
+		mux:    http.NewServeMux(),
+		ctx:    ctx,
+		cancel: cancel,
+		jobs:   map[string]*job{},
+	}
+	s.mux.HandleFunc("POST /api/scans", s.submitScan)
+	s.mux.HandleFunc("GET /api/scans", s.listScans)
+	s.mux.HandleFunc("GET /api/scans/{id}", s.getScan)
+	s.mux.HandleFunc("DELETE /api/scans/{id}", s.cancelScan)
+	s.mux.HandleFunc("GET /api/scans/{id}/results", s.scanResults)
+	s.mux.HandleFunc("GET /api/scans/{id}/events", s.scanEvents)
+	s.mux.HandleFunc("GET /api/hosts", s.listHosts)
+	s.mux.HandleFunc("GET /api/hosts/{ip}", s.getHost)
+	s.mux.HandleFunc("GET /api/models", s.listModels)
+	s.mux.HandleFunc("GET /api/export", s.export)
+	return s
+}
+
+// Handle 在 API 路由之外注册额外的处理器,例如静态页面
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+// ServeHTTP 校验令牌后分发请求,只有 /api/ 下的接口需要令牌
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Token != "" && strings.HasPrefix(r.URL.Path, "/api/") {
+		got := r.Header.Get("Authorization")
+		if t := r.URL.Query().Get("token"); t != "" && queryTokenAllowed(r) {
+			got = "Bearer " + t
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+s.cfg.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "缺少或错误的访问令牌")
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// queryTokenAllowed 判断请求能否通过 token 查询参数传递令牌.
+// 浏览器的 EventSource 和下载链接无法设置请求头,只有这两类只读接口接受查询参数,
+// 其他接口必须使用 Authorization 请求头,避免令牌出现在代理日志和浏览历史中
+func queryTokenAllowed(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	if r.URL.Path == "/api/export" {
+		return true
+	}
+	id, ok := strings.CutPrefix(r.URL.Path, "/api/scans/")
+	return ok && strings.HasSuffix(id, "/events") && strings.Count(id, "/") == 1
+}
+
+// Close 取消所有进行中的扫描并等待它们保存结果
+func (s *Server) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Server) now() time.Time {
+	return time.Now().In(s.cfg.Location)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,58 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aspnmy/ollama_scanner/api"
+	"github.com/aspnmy/ollama_scanner/store"
+)
+
+func TestAuth(t *testing.T) {
+	st, err := store.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	srv := httptest.NewServer(api.New(api.Config{Store: st, Token: "secret"}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header string
+		want   int
+	}{
+		{"缺少令牌", http.MethodGet, "/api/hosts", "", http.StatusUnauthorized},
+		{"错误令牌", http.MethodGet, "/api/hosts", "Bearer wrong", http.StatusUnauthorized},
+		{"请求头令牌", http.MethodGet, "/api/hosts", "Bearer secret", http.StatusOK},
+		{"导出接受查询参数", http.MethodGet, "/api/export?format=json&token=secret", "", http.StatusOK},
+		{"事件流接受查询参数", http.MethodGet, "/api/scans/missing/events?token=secret", "", http.StatusNotFound},
+		{"查询接口不接受查询参数", http.MethodGet, "/api/hosts?token=secret", "", http.StatusUnauthorized},
+		{"提交扫描不接受查询参数", http.MethodPost, "/api/scans?token=secret", "", http.StatusUnauthorized},
+		{"取消扫描不接受查询参数", http.MethodDelete, "/api/scans/missing/events?token=secret", "", http.StatusUnauthorized},
+		{"页面不需要令牌", http.MethodGet, "/missing", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader("{}"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("状态码 = %d, 期望 %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+// Package bench 对 Ollama 模型执行生成性能测试,并限制测试范围和资源消耗.
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/probe"
+	"github.com/aspnmy/ollama_scanner/scope"
+)
+
+const (
+	DefaultPrompt     = "为什么太阳会发光？用一句话回答"
+	DefaultNumPredict = 128 // 单次性能测试最多生成的 token 数
+	DefaultTimeout    = 30 * time.Second
+	MaxNumPredict     = 1024
+	MaxTimeout        = 120 * time.Second
+	DefaultRuns       = 1
+	MaxRuns           = 20 // 单个模型最多正式测量的次数
+	MaxWarmupRuns     = 5
+)
+
+// Config 为性能测试配置
+type Config struct {
+	Prompt     string
+	NumPredict int
+	Timeout    time.Duration
+	// Owned 为允许执行性能测试的自有端点,为空时不对任何主机执行性能测试
+	Owned []netip.Prefix
+	// MaxModelSizeGB 大于 0 时,超过该大小的模型不执行性能测试
+	MaxModelSizeGB float64
+	// WarmupRuns 为正式测量前执行并丢弃的预热次数
+	WarmupRuns int
+	// Runs 为正式测量次数,结果取中位数并计算分位数和标准差
+	Runs int
+	// ColdStart 为 true 时先卸载模型,再单独测量一次包含加载时间的冷启动
+	ColdStart bool
+	// EmbedBatchSizes 为嵌入模型依次测试的每批输入数量
+	EmbedBatchSizes []int
+	// Mode 为生成模型的测试模式,chat 时按 Conversation 执行多轮对话测试
+	Mode         string
+	Conversation Conversation
+	// Smoke 为冒烟测试用例,没有用例时不执行冒烟测试
+	Smoke SmokeSuite
+}
+
+// requests 返回测试计划中的生成请求次数
+func (c Config) requests() int {
+	n := c.WarmupRuns + c.Runs
+	if c.ColdStart {
+		n++
+	}
+	return n
+}
+
+// Result 为单个模型的性能测试结果,FirstTokenDelay 和 TokensPerSec 为正式测量的中位数.
+// 测试计划只有一次请求时 Stats 和 Samples 为空.
+type Result struct {
+	FirstTokenDelay time.Duration
+	TokensPerSec    float64
+	Status          string
+	Stats           *Stats
+	Samples         []Sample
+	// Embedding 为嵌入模型的测试结果,生成模型为空
+	Embedding *EmbedResult
+	// Chat 为对话模式的测试结果
+	Chat *ChatResult
+}
+
+// Benchmarker 使用与探测相同的 HTTP 客户端和限速器执行性能测试
+type Benchmarker struct {
+	Config Config
+	Prober *probe.Prober
+}
+
+// New 创建性能测试器,并将生成长度和超时限制在上限以内
+func New(cfg Config, prober *probe.Prober) *Benchmarker {
+	if cfg.Prompt == "" {
+		cfg.Prompt = DefaultPrompt
+	}
+	if cfg.NumPredict <= 0 {
+		cfg.NumPredict = DefaultNumPredict
+	}
+	cfg.NumPredict = min(cfg.NumPredict, MaxNumPredict)
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	cfg.Timeout = min(cfg.Timeout, MaxTimeout)
+	if cfg.Runs <= 0 {
+		cfg.Runs = DefaultRuns
+	}
+	cfg.Runs = min(cfg.Runs, MaxRuns)
+	cfg.WarmupRuns = min(max(cfg.WarmupRuns, 0), MaxWarmupRuns)
+	cfg.EmbedBatchSizes = slices.DeleteFunc(slices.Clone(cfg.EmbedBatchSizes), func(n int) bool {
+		return n <= 0 || n > MaxEmbedBatchSize
+	})
+	if len(cfg.EmbedBatchSizes) == 0 {
+		cfg.EmbedBatchSizes = DefaultEmbedBatchSizes
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = ModeGenerate
+	}
+	return &Benchmarker{Config: cfg, Prober: prober}
+}
+
+// SkipReason 返回不对该模型执行性能测试的原因,允许测试时返回空字符串
+func (b *Benchmarker) SkipReason(ip string, modelSize int64) string {
+	if !scope.Match(b.Config.Owned, ip) {
+		return "非自有端点，未执行性能测试"
+	}
+	if b.Config.MaxModelSizeGB > 0 {
+		sizeGB := float64(modelSize) / 1024 / 1024 / 1024
+		if sizeGB > b.Config.MaxModelSizeGB {
+			return fmt.Sprintf("模型大小 %.1f GB 超过上限 %.1f GB", sizeGB, b.Config.MaxModelSizeGB)
+		}
+	}
+	return ""
+}
+
+// Run 按测试计划对单个模型执行冷启动、预热和多次正式测量,
+// 返回正式测量的中位数、统计值和所有原始样本
+func (b *Benchmarker) Run(ctx context.Context, ip string, model string) Result {
+	total := b.Config.requests()
+	keepAlive := any(0)
+	if total > 1 {
+		// 多次测量之间保持模型加载,全部结束后再卸载
+		keepAlive = planKeepAlive
+		defer b.unload(context.WithoutCancel(ctx), ip, model)
+	}
+
+	var samples []Sample
+	if b.Config.ColdStart {
+		if err := b.unload(ctx, ip, model); err != nil {
+			samples = append(samples, Sample{Phase: PhaseCold, StartedAt: time.Now(), Status: err.Error()})
+		} else {
+			samples = append(samples, b.runOnce(ctx, ip, model, PhaseCold, keepAlive))
+		}
+	}
+	for range b.Config.WarmupRuns {
+		samples = append(samples, b.runOnce(ctx, ip, model, PhaseWarmup, keepAlive))
+	}
+	for range b.Config.Runs {
+		samples = append(samples, b.runOnce(ctx, ip, model, PhaseMeasure, keepAlive))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return summarize(samples, total > 1)
+}
+
+// runOnce 执行一次流式生成并统计首 token 延迟和生成速度.
+// 响应中带有 eval_count 和 eval_duration 时按服务端统计计算速度,否则按收到的片段数和耗时计算.
+func (b *Benchmarker) runOnce(ctx context.Context, ip string, model string, phase string, keepAlive any) Sample {
+	sample := Sample{Phase: phase, StartedAt: time.Now()}
+	if err := b.Prober.Limiter.Wait(ctx, ip); err != nil {
+		sample.Status = "已取消"
+		return sample
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.Config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	// 限制生成长度,单次测试时在测试结束后立即卸载模型
+	payload := map[string]interface{}{
+		"model":      model,
+		"prompt":     b.Config.Prompt,
+		"stream":     true,
+		"keep_alive": keepAlive,
+		"options": map[string]interface{}{
+			"num_predict": b.Config.NumPredict,
+		},
+	}
+
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		b.Prober.BaseURL(ip)+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		sample.Status = "请求构造失败"
+		return sample
+	}
+	resp, err := b.Prober.Client.Do(req)
+	if err != nil {
+		sample.Status = failureStatus(ctx, "连接失败")
+		return sample
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		sample.Status = fmt.Sprintf("HTTP错误: %d", resp.StatusCode)
+		return sample
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	var (
+		firstToken time.Time
+		lastToken  time.Time
+		chunks     int
+		malformed  int
+		final      *chunk
+	)
+	for scanner.Scan() {
+		var data chunk
+		if err := json.Unmarshal(scanner.Bytes(), &data); err != nil {
+			malformed++
+			continue
+		}
+		if chunks == 0 {
+			firstToken = time.Now()
+		}
+		lastToken = time.Now()
+		chunks++
+		if data.Done {
+			final = &data
+			break
+		}
+	}
+
+	switch {
+	case final == nil && scanner.Err() != nil:
+		sample.Status = failureStatus(ctx, "读取响应失败")
+		return sample
+	case malformed > 0:
+		// 无法解析的片段会让首 token 时间和片段计数失真
+		sample.Status = "响应格式错误"
+		return sample
+	case chunks == 0:
+		sample.Status = "无响应"
+		return sample
+	case final == nil:
+		sample.Status = "响应不完整"
+		return sample
+	}
+
+	sample.FirstTokenDelay = firstToken.Sub(start)
+	sample.Duration = lastToken.Sub(start)
+	sample.LoadDuration = time.Duration(final.LoadDuration)
+	if final.EvalCount > 0 && final.EvalDuration > 0 {
+		sample.Tokens = final.EvalCount
+		sample.TokensPerSec = float64(final.EvalCount) / time.Duration(final.EvalDuration).Seconds()
+	} else {
+		sample.Tokens = chunks
+		sample.TokensPerSec = float64(chunks) / lastToken.Sub(start).Seconds()
+	}
+	sample.Status = "完成"
+	return sample
+}
+
+// chunk 为 /api/generate 流式响应中的一行,统计字段只在最后一行出现
+type chunk struct {
+	Done         bool  `json:"done"`
+	LoadDuration int64 `json:"load_duration"`
+	EvalCount    int   `json:"eval_count"`
+	EvalDuration int64 `json:"eval_duration"`
+}
+
+// unload 发送不带提示词且 keep_alive 为 0 的请求卸载模型,模型未加载时同样返回成功
+func (b *Benchmarker) unload(ctx context.Context, ip string, model string) error {
+	if err := b.Prober.Limiter.Wait(ctx, ip); err != nil {
+		return errors.New("已取消")
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.Config.Timeout)
+	defer cancel()
+	body, _ := json.Marshal(map[string]interface{}{"model": model, "keep_alive": 0, "stream": false})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		b.Prober.BaseURL(ip)+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		return errors.New("请求构造失败")
+	}
+	resp, err := b.Prober.Client.Do(req)
+	if err != nil {
+		return errors.New("卸载模型失败")
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("卸载模型失败: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// failureStatus 区分超时、取消和其他失败
+func failureStatus(ctx context.Context, status string) string {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return "超时"
+	case ctx.Err() != nil:
+		return "已取消"
+	}
+	return status
+}
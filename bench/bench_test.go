@@ -0,0 +1,308 @@
+package bench_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/bench"
+	"github.com/aspnmy/ollama_scanner/mock"
+	"github.com/aspnmy/ollama_scanner/probe"
+)
+
+const (
+	testModel      = "qwen2.5:0.5b"
+	testEmbedModel = "nomic-embed-text:latest"
+)
+
+// newBenchmarker 启动模拟服务并返回指向它的性能测试器和地址,模拟服务输出速度足够快以缩短测试时间
+func newBenchmarker(t *testing.T, mcfg mock.Config, cfg bench.Config) (*bench.Benchmarker, string) {
+	t.Helper()
+	if mcfg.Models == nil {
+		mcfg.Models = []mock.Model{mock.NewModel(testModel), mock.NewModel(testEmbedModel)}
+	}
+	if mcfg.TokensPerSec == 0 {
+		mcfg.TokensPerSec = 2000
+	}
+	srv := httptest.NewServer(mock.Handler(mcfg))
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+	p := &probe.Prober{
+		Port:    port,
+		Timeout: 5 * time.Second,
+		Client:  probe.NewClient(probe.ClientConfig{}),
+		Limiter: probe.NewLimiter(probe.LimitConfig{}),
+	}
+	if cfg.NumPredict == 0 {
+		cfg.NumPredict = 8
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	cfg.Owned = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+	return bench.New(cfg, p), u.Hostname()
+}
+
+func TestSkipReason(t *testing.T) {
+	b, _ := newBenchmarker(t, mock.Config{}, bench.Config{MaxModelSizeGB: 1})
+	tests := []struct {
+		ip   string
+		size int64
+		want string
+	}{
+		{"127.0.0.1", 1 << 29, ""},
+		{"10.0.0.1", 1 << 29, "非自有端点，未执行性能测试"},
+		{"127.0.0.1", 2 << 30, "模型大小 2.0 GB 超过上限 1.0 GB"},
+	}
+	for _, tt := range tests {
+		if got := b.SkipReason(tt.ip, tt.size); got != tt.want {
+			t.Errorf("SkipReason(%s, %d) = %q, 期望 %q", tt.ip, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestRunSingle(t *testing.T) {
+	b, ip := newBenchmarker(t, mock.Config{}, bench.Config{})
+	r := b.Run(context.Background(), ip, testModel)
+	if r.Status != "完成" {
+		t.Fatalf("Status = %q", r.Status)
+	}
+	if r.FirstTokenDelay <= 0 || r.TokensPerSec <= 0 {
+		t.Fatalf("FirstTokenDelay = %v TokensPerSec = %v", r.FirstTokenDelay, r.TokensPerSec)
+	}
+	// 单次请求不附带统计值和样本
+	if r.Stats != nil || r.Samples != nil {
+		t.Fatalf("单次测试不应有统计值: %+v", r)
+	}
+}
+
+func TestRunPlan(t *testing.T) {
+	b, ip := newBenchmarker(t, mock.Config{}, bench.Config{WarmupRuns: 1, Runs: 3, ColdStart: true})
+	r := b.Run(context.Background(), ip, testModel)
+	if r.Status != "完成" || r.Stats == nil {
+		t.Fatalf("Result = %+v", r)
+	}
+	var phases []string
+	for _, s := range r.Samples {
+		phases = append(phases, s.Phase)
+		if !s.OK() || s.Tokens != 8 {
+			t.Errorf("样本 %+v 未成功完成", s)
+		}
+	}
+	want := []string{bench.PhaseCold, bench.PhaseWarmup, bench.PhaseMeasure, bench.PhaseMeasure, bench.PhaseMeasure}
+	if !slices.Equal(phases, want) {
+		t.Fatalf("样本阶段 = %v, 期望 %v", phases, want)
+	}
+	s := r.Stats
+	if s.Runs != 3 || s.Failed != 0 || s.Cold == nil || s.Cold.Phase != bench.PhaseCold {
+		t.Fatalf("Stats = %+v", s)
+	}
+	if r.FirstTokenDelay != s.FirstTokenP50 || r.TokensPerSec != s.TokensPerSecP50 {
+		t.Errorf("结果应为正式测量的中位数: %+v", r)
+	}
+	if s.FirstTokenP50 > s.FirstTokenP90 || s.FirstTokenP90 > s.FirstTokenP99 {
+		t.Errorf("分位数未按顺序: %+v", s)
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		failure mock.Failure
+		model   string
+		want    string
+	}{
+		{"格式错误", mock.FailureMalformedJSON, testModel, "响应格式错误"},
+		{"永不结束", mock.FailureEndlessStream, testModel, "超时"},
+		{"模型不存在", mock.FailureNone, "missing:1b", "HTTP错误: 404"},
+		{"嵌入模型不支持生成", mock.FailureNone, testEmbedModel, "HTTP错误: 400"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, ip := newBenchmarker(t, mock.Config{Failure: tt.failure}, bench.Config{Timeout: 300 * time.Millisecond})
+			if r := b.Run(context.Background(), ip, tt.model); r.Status != tt.want {
+				t.Fatalf("Status = %q, 期望 %q", r.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunEmbed(t *testing.T) {
+	b, ip := newBenchmarker(t, mock.Config{}, bench.Config{EmbedBatchSizes: []int{1, 4}, Runs: 2})
+	r := b.RunEmbed(context.Background(), ip, testEmbedModel)
+	if r.Status != "完成" || r.Embedding == nil {
+		t.Fatalf("Result = %+v", r)
+	}
+	e := r.Embedding
+	if e.Dimension != mock.EmbeddingDimension || len(e.Batches) != 2 {
+		t.Fatalf("Embedding = %+v", e)
+	}
+	for i, size := range []int{1, 4} {
+		batch := e.Batches[i]
+		if batch.BatchSize != size || batch.Runs != 2 || batch.Failed != 0 || batch.Status != "完成" ||
+			batch.Latency <= 0 || batch.InputsPerSec <= 0 {
+			t.Errorf("Batches[%d] = %+v", i, batch)
+		}
+	}
+	if !bench.IsEmbedding(mock.EmbeddingFamily, nil) || bench.IsEmbedding("qwen2", []string{"completion"}) {
+		t.Error("IsEmbedding 判断错误")
+	}
+}
+
+func TestRunChat(t *testing.T) {
+	conv := bench.Conversation{System: "简洁回答", Turns: []string{"第一轮", "第二轮", "第三轮"}}
+	b, ip := newBenchmarker(t, mock.Config{}, bench.Config{Mode: bench.ModeChat, Conversation: conv})
+	r := b.RunChat(context.Background(), ip, testModel)
+	if r.Status != "完成" || r.Chat == nil {
+		t.Fatalf("Result = %+v", r)
+	}
+	turns := r.Chat.Turns
+	if len(turns) != 3 {
+		t.Fatalf("Turns = %+v", turns)
+	}
+	for i, turn := range turns {
+		// 系统消息 + 之前每轮的用户消息和回复 + 本轮用户消息
+		if turn.Run != 1 || turn.Turn != i+1 || turn.Messages != 2*i+2 || turn.Status != "完成" || turn.Tokens != 8 {
+			t.Errorf("Turns[%d] = %+v", i, turn)
+		}
+		// 模拟服务不复用缓存,提示词 token 数即为上下文长度
+		if turn.ContextTokens != turn.PromptTokens {
+			t.Errorf("Turns[%d] ContextTokens = %d, 期望 %d", i, turn.ContextTokens, turn.PromptTokens)
+		}
+		if i > 0 && turn.PromptTokens <= turns[i-1].PromptTokens {
+			t.Errorf("第 %d 轮提示词 token 数未随历史增长: %d <= %d", i+1, turn.PromptTokens, turns[i-1].PromptTokens)
+		}
+	}
+	if r.Chat.PromptMsPer1K <= 0 {
+		t.Errorf("PromptMsPer1K = %v", r.Chat.PromptMsPer1K)
+	}
+}
+
+func TestRunChatRuns(t *testing.T) {
+	conv := bench.Conversation{Turns: []string{"第一轮", "第二轮"}}
+	b, ip := newBenchmarker(t, mock.Config{}, bench.Config{Mode: bench.ModeChat, Conversation: conv, Runs: 3})
+	r := b.RunChat(context.Background(), ip, testModel)
+	if r.Status != "完成" || r.Chat == nil || r.Chat.Runs != 3 || len(r.Chat.Turns) != 6 {
+		t.Fatalf("Result = %+v", r)
+	}
+	for i, turn := range r.Chat.Turns {
+		// 每次执行从空的历史开始
+		if turn.Run != i/2+1 || turn.Turn != i%2+1 || turn.Messages != 2*(i%2)+1 {
+			t.Errorf("Turns[%d] = %+v", i, turn)
+		}
+	}
+}
+
+func TestRunChatStopsAfterFailure(t *testing.T) {
+	conv := bench.Conversation{Turns: []string{"a", "b"}}
+	b, ip := newBenchmarker(t, mock.Config{}, bench.Config{Mode: bench.ModeChat, Conversation: conv})
+	b.Config.Runs = 2
+	r := b.RunChat(context.Background(), ip, "missing:1b")
+	if r.Status != "HTTP错误: 404" || len(r.Chat.Turns) != 1 {
+		t.Fatalf("Result = %+v, Turns = %+v", r, r.Chat.Turns)
+	}
+}
+
+func TestRunSmoke(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "smoke.yml")
+	err := os.WriteFile(path, []byte(`
+tests:
+  - name: contains
+    prompt: 为什么太阳会发光？
+    contains: 核聚变
+  - name: regex
+    prompt: 太阳
+    regex: '^太阳发光'
+  - name: wrong
+    prompt: 法国首都？
+    contains: 巴黎
+  - name: llama-only
+    prompt: hi
+    contains: 核聚变
+    models: [llama]
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	suite, err := bench.LoadSmokeTests(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if suite.Seed != bench.DefaultSmokeSeed || suite.NumPredict != bench.DefaultSmokeNumPredict {
+		t.Fatalf("默认值 seed=%d num_predict=%d", suite.Seed, suite.NumPredict)
+	}
+	b, ip := newBenchmarker(t, mock.Config{}, bench.Config{Smoke: suite})
+	r := b.RunSmoke(context.Background(), ip, testModel)
+	if r == nil || r.Passed != 2 || r.Failed != 1 || len(r.Checks) != 3 {
+		t.Fatalf("SmokeResult = %+v", r)
+	}
+	if got := r.FailedNames(); !slices.Equal(got, []string{"wrong"}) {
+		t.Errorf("FailedNames = %v", got)
+	}
+	if c := r.Checks[2]; c.Status != "未通过" || c.Response == "" {
+		t.Errorf("Checks[2] = %+v", c)
+	}
+
+	// 没有适用用例时返回 nil
+	b.Config.Smoke.Tests = suite.Tests[3:]
+	if r := b.RunSmoke(context.Background(), ip, testModel); r != nil {
+		t.Errorf("没有适用用例时应返回 nil: %+v", r)
+	}
+}
+
+func TestLoadSmokeTestsSeed(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{"tests:\n  - {prompt: hi, contains: x}\n", bench.DefaultSmokeSeed},
+		{"seed: 0\ntests:\n  - {prompt: hi, contains: x}\n", 0},
+		{"seed: 7\ntests:\n  - {prompt: hi, contains: x}\n", 7},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "smoke.yml")
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		suite, err := bench.LoadSmokeTests(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if suite.Seed != tt.want {
+			t.Errorf("LoadSmokeTests(%q) Seed = %d, 期望 %d", tt.content, suite.Seed, tt.want)
+		}
+	}
+}
+
+func TestLoadSmokeTestsInvalid(t *testing.T) {
+	tests := map[string]string{
+		"没有用例":  "seed: 1\n",
+		"缺少预期":  "tests:\n  - prompt: hi\n",
+		"预期重复":  "tests:\n  - prompt: hi\n    contains: a\n    regex: b\n",
+		"正则无效":  "tests:\n  - prompt: hi\n    regex: '('\n",
+		"名称重复":  "tests:\n  - {name: a, prompt: hi, contains: x}\n  - {name: a, prompt: hi, contains: x}\n",
+		"提示词为空": "tests:\n  - {name: a, prompt: ' ', contains: x}\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "smoke.yml")
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := bench.LoadSmokeTests(path); err == nil {
+				t.Fatal("期望返回错误")
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 性能测试模式
+const (
+	ModeGenerate = "generate" // 单轮 /api/generate
+	ModeChat     = "chat"     // 按对话脚本多轮 /api/chat
+)
+
+// MaxChatTurns 为对话脚本的最大轮数
+const MaxChatTurns = 20
+
+// Conversation 为对话测试脚本,每轮发送一条用户消息,模型的回复会加入后续轮次的历史
+type Conversation struct {
+	System string   `yaml:"system" json:"system,omitempty"`
+	Turns  []string `yaml:"turns" json:"turns"`
+}
+
+// DefaultConversation 为未指定对话脚本时使用的多轮对话
+var DefaultConversation = Conversation{
+	Turns: []string{
+		"为什么太阳会发光？用一句话回答",
+		"太阳核心的温度大约是多少？",
+		"太阳还能燃烧多久？",
+		"用三句话总结我们刚才讨论的内容",
+	},
+}
+
+// LoadConversation 读取 YAML 或 JSON 格式的对话脚本
+func LoadConversation(path string) (Conversation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Conversation{}, err
+	}
+	var c Conversation
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return Conversation{}, fmt.Errorf("解析对话脚本 %s 失败: %w", path, err)
+	}
+	switch {
+	case len(c.Turns) == 0:
+		return Conversation{}, fmt.Errorf("对话脚本 %s 中没有 turns", path)
+	case len(c.Turns) > MaxChatTurns:
+		return Conversation{}, fmt.Errorf("对话脚本 %s 有 %d 轮,最多 %d 轮", path, len(c.Turns), MaxChatTurns)
+	case slices.ContainsFunc(c.Turns, func(t string) bool { return strings.TrimSpace(t) == "" }):
+		return Conversation{}, fmt.Errorf("对话脚本 %s 中有空的用户消息", path)
+	}
+	return c, nil
+}
+
+// ChatTurn 为对话中一轮的测量结果
+type ChatTurn struct {
+	// Run 为第几次执行对话脚本,Turn 为脚本中的第几轮,均从 1 开始
+	Run  int `json:"run"`
+	Turn int `json:"turn"`
+	// Messages 为本轮请求包含的消息数,包括系统消息和之前的历史
+	Messages        int           `json:"messages"`
+	FirstTokenDelay time.Duration `json:"first_token_delay_ns"`
+	TokensPerSec    float64       `json:"tokens_per_sec"`
+	Tokens          int           `json:"tokens"`
+	// PromptTokens 和 PromptDuration 为服务端报告的提示词处理量和耗时,
+	// 服务端复用上一轮缓存时只包含新增部分
+	PromptTokens   int           `json:"prompt_tokens"`
+	PromptDuration time.Duration `json:"prompt_duration_ns"`
+	// ContextTokens 为本轮请求的上下文长度估计,包括之前各轮的提示词和回复
+	ContextTokens int    `json:"context_tokens"`
+	Status        string `json:"status"`
+}
+
+// Label 返回本轮的名称,例如 "第 2 轮",对话脚本执行多次时为 "第 1 次第 2 轮"
+func (t ChatTurn) Label(runs int) string {
+	if runs > 1 {
+		return fmt.Sprintf("第 %d 次第 %d 轮", t.Run, t.Turn)
+	}
+	return fmt.Sprintf("第 %d 轮", t.Turn)
+}
+
+// ChatResult 为多轮对话测试结果
+type ChatResult struct {
+	// Runs 为对话脚本的执行次数,Turns 依次包含每次执行的各轮结果
+	Runs  int        `json:"runs"`
+	Turns []ChatTurn `json:"turns"`
+	// PromptMsPer1K 为提示词处理时间随上下文长度增长的斜率(每千 token 毫秒数),
+	// 按各轮数据最小二乘拟合,少于两轮有效数据时为 0
+	PromptMsPer1K float64 `json:"prompt_ms_per_1k_tokens"`
+}
+
+// chatMessage 为 /api/chat 的消息
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// RunChat 按对话脚本依次发送每轮用户消息,并把模型的回复加入历史,
+// 测量每轮的首 token 延迟、生成速度和提示词处理时间.整个脚本执行 Runs 次,
+// 每次从空的历史开始.某一轮失败时停止后续轮次和执行.
+// 返回结果中的 FirstTokenDelay 和 TokensPerSec 为所有成功轮次的中位数.
+func (b *Benchmarker) RunChat(ctx context.Context, ip string, model string) Result {
+	defer b.unload(context.WithoutCancel(ctx), ip, model)
+	conv := b.Config.Conversation
+	if len(conv.Turns) == 0 {
+		conv = DefaultConversation
+	}
+	// 预热只加载模型,不计入对话历史
+	for range b.Config.WarmupRuns {
+		b.runOnce(ctx, ip, model, PhaseWarmup, planKeepAlive)
+	}
+
+	var (
+		res    = ChatResult{Runs: max(b.Config.Runs, 1)}
+		ttft   []float64
+		tps    []float64
+		status string
+	)
+	for run := 1; run <= res.Runs && status == ""; run++ {
+		var history []chatMessage
+		if conv.System != "" {
+			history = append(history, chatMessage{Role: "system", Content: conv.System})
+		}
+		var prev *ChatTurn
+		for i, content := range conv.Turns {
+			history = append(history, chatMessage{Role: "user", Content: content})
+			turn, reply := b.chatOnce(ctx, ip, model, history)
+			turn.Run, turn.Turn = run, i+1
+			if turn.Status != "完成" {
+				res.Turns = append(res.Turns, turn)
+				status = turn.Status
+				break
+			}
+			turn.ContextTokens = contextTokens(prev, turn)
+			res.Turns = append(res.Turns, turn)
+			prev = &res.Turns[len(res.Turns)-1]
+			history = append(history, chatMessage{Role: "assistant", Content: reply})
+			ttft = append(ttft, float64(turn.FirstTokenDelay))
+			tps = append(tps, turn.TokensPerSec)
+		}
+	}
+	res.PromptMsPer1K = promptSlope(res.Turns)
+
+	if len(ttft) == 0 {
+		return Result{Status: cmp.Or(status, "已取消"), Chat: &res}
+	}
+	slices.Sort(ttft)
+	slices.Sort(tps)
+	r := Result{
+		FirstTokenDelay: time.Duration(percentile(ttft, 50)),
+		TokensPerSec:    percentile(tps, 50),
+		Status:          "完成",
+		Chat:            &res,
+	}
+	if status != "" {
+		r.Status = fmt.Sprintf("%s失败: %s", res.Turns[len(res.Turns)-1].Label(res.Runs), status)
+	}
+	return r
+}
+
+// chatOnce 发送一轮流式 /api/chat 请求,返回测量结果和完整回复
+func (b *Benchmarker) chatOnce(ctx context.Context, ip string, model string, history []chatMessage) (ChatTurn, string) {
+	turn := ChatTurn{Messages: len(history)}
+	if err := b.Prober.Limiter.Wait(ctx, ip); err != nil {
+		turn.Status = "已取消"
+		return turn, ""
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.Config.Timeout)
+	defer cancel()
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      model,
+		"messages":   history,
+		"stream":     true,
+		"keep_alive": planKeepAlive,
+		"options": map[string]interface{}{
+			"num_predict": b.Config.NumPredict,
+		},
+	})
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		b.Prober.BaseURL(ip)+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		turn.Status = "请求构造失败"
+		return turn, ""
+	}
+	resp, err := b.Prober.Client.Do(req)
+	if err != nil {
+		turn.Status = failureStatus(ctx, "连接失败")
+		return turn, ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		turn.Status = fmt.Sprintf("HTTP错误: %d", resp.StatusCode)
+		return turn, ""
+	}
+
+	var (
+		reply      strings.Builder
+		firstToken time.Time
+		final      *chatChunk
+		malformed  bool
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var data chatChunk
+		if err := json.Unmarshal(scanner.Bytes(), &data); err != nil {
+			malformed = true
+			break
+		}
+		if firstToken.IsZero() && data.Message.Content != "" {
+			firstToken = time.Now()
+		}
+		reply.WriteString(data.Message.Content)
+		if data.Done {
+			final = &data
+			break
+		}
+	}
+	switch {
+	case malformed:
+		turn.Status = "响应格式错误"
+		return turn, ""
+	case final == nil && scanner.Err() != nil:
+		turn.Status = failureStatus(ctx, "读取响应失败")
+		return turn, ""
+	case final == nil:
+		turn.Status = "响应不完整"
+		return turn, ""
+	case firstToken.IsZero():
+		turn.Status = "无响应"
+		return turn, ""
+	}
+
+	turn.FirstTokenDelay = firstToken.Sub(start)
+	turn.Tokens = final.EvalCount
+	if final.EvalDuration > 0 {
+		turn.TokensPerSec = float64(final.EvalCount) / time.Duration(final.EvalDuration).Seconds()
+	}
+	turn.PromptTokens = final.PromptEvalCount
+	turn.PromptDuration = time.Duration(final.PromptEvalDuration)
+	turn.Status = "完成"
+	return turn, reply.String()
+}
+
+// chatChunk 为 /api/chat 流式响应中的一行,统计字段只在最后一行出现
+type chatChunk struct {
+	Message            chatMessage `json:"message"`
+	Done               bool        `json:"done"`
+	EvalCount          int         `json:"eval_count"`
+	EvalDuration       int64       `json:"eval_duration"`
+	PromptEvalCount    int         `json:"prompt_eval_count"`
+	PromptEvalDuration int64       `json:"prompt_eval_duration"`
+}
+
+// contextTokens 估计本轮请求的上下文长度.服务端没有复用缓存时提示词 token 数已包含全部历史;
+// 复用缓存时只包含新增部分,需要加上上一轮的上下文和回复.
+func contextTokens(prev *ChatTurn, turn ChatTurn) int {
+	if prev == nil {
+		return turn.PromptTokens
+	}
+	history := prev.ContextTokens + prev.Tokens
+	if turn.PromptTokens >= history {
+		return turn.PromptTokens
+	}
+	return history + turn.PromptTokens
+}
+
+// promptSlope 对成功轮次的上下文长度和提示词处理时间做最小二乘拟合,返回每千 token 的毫秒数.
+// 按上下文长度而不是本轮处理的 token 数拟合,服务端复用缓存时斜率仍反映历史增长的影响.
+func promptSlope(turns []ChatTurn) float64 {
+	var xs, ys []float64
+	for _, t := range turns {
+		if t.Status == "完成" && t.ContextTokens > 0 {
+			xs = append(xs, float64(t.ContextTokens))
+			ys = append(ys, t.PromptDuration.Seconds()*1000)
+		}
+	}
+	if len(xs) < 2 {
+		return 0
+	}
+	var mx, my float64
+	for i := range xs {
+		mx += xs[i]
+		my += ys[i]
+	}
+	mx /= float64(len(xs))
+	my /= float64(len(xs))
+	var num, den float64
+	for i := range xs {
+		num += (xs[i] - mx) * (ys[i] - my)
+		den += (xs[i] - mx) * (xs[i] - mx)
+	}
+	if den == 0 {
+		return 0
+	}
+	return num / den * 1000
+}
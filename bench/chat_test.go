@@ -0,0 +1,49 @@
+package bench
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestContextTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		prev *ChatTurn
+		turn ChatTurn
+		want int
+	}{
+		{"第一轮", nil, ChatTurn{PromptTokens: 20}, 20},
+		{"未复用缓存", &ChatTurn{ContextTokens: 20, Tokens: 8}, ChatTurn{PromptTokens: 38}, 38},
+		{"复用缓存", &ChatTurn{ContextTokens: 20, Tokens: 8}, ChatTurn{PromptTokens: 10}, 38},
+		{"连续复用缓存", &ChatTurn{ContextTokens: 38, Tokens: 8}, ChatTurn{PromptTokens: 12}, 58},
+	}
+	for _, tt := range tests {
+		if got := contextTokens(tt.prev, tt.turn); got != tt.want {
+			t.Errorf("%s: contextTokens = %d, 期望 %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPromptSlope(t *testing.T) {
+	turn := func(context int, ms float64) ChatTurn {
+		return ChatTurn{ContextTokens: context, PromptDuration: time.Duration(ms * float64(time.Millisecond)), Status: "完成"}
+	}
+	tests := []struct {
+		name  string
+		turns []ChatTurn
+		want  float64
+	}{
+		{"没有数据", nil, 0},
+		{"只有一轮", []ChatTurn{turn(100, 10)}, 0},
+		{"上下文长度相同", []ChatTurn{turn(100, 10), turn(100, 20)}, 0},
+		// 每增加 1000 token 多 50ms
+		{"线性增长", []ChatTurn{turn(1000, 60), turn(2000, 110), turn(4000, 210)}, 50},
+		{"失败和无数据的轮次被忽略", []ChatTurn{turn(500, 30), {Status: "超时"}, turn(0, 0), turn(1500, 80)}, 50},
+	}
+	for _, tt := range tests {
+		if got := promptSlope(tt.turns); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: promptSlope = %v, 期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
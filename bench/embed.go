@@ -0,0 +1,146 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// ModelTypeEmbedding 为只支持 /api/embed 的嵌入模型
+	ModelTypeEmbedding = "embedding"
+	MaxEmbedBatchSize  = 256
+)
+
+// DefaultEmbedBatchSizes 为嵌入性能测试默认的批量大小
+var DefaultEmbedBatchSizes = []int{1, 8, 32}
+
+// embeddingFamilies 为常见嵌入模型的架构,/api/show 没有返回能力列表时据此判断
+var embeddingFamilies = []string{"bert", "nomic-bert", "nomic-bert-moe", "jina-bert", "jina-bert-v2", "xlm-roberta", "xlmroberta"}
+
+// IsEmbedding 根据 /api/show 的能力列表判断是否为嵌入模型,
+// 旧版本 Ollama 不返回能力列表,此时按模型架构判断
+func IsEmbedding(family string, capabilities []string) bool {
+	if len(capabilities) > 0 {
+		return slices.Contains(capabilities, "embedding") && !slices.Contains(capabilities, "completion")
+	}
+	return slices.Contains(embeddingFamilies, strings.ToLower(family))
+}
+
+// EmbedBatch 为一个批量大小的嵌入测试结果,Latency 为正式测量的中位数
+type EmbedBatch struct {
+	BatchSize    int           `json:"batch_size"`
+	Runs         int           `json:"runs"`
+	Failed       int           `json:"failed"`
+	Latency      time.Duration `json:"latency_ns"`
+	InputsPerSec float64       `json:"inputs_per_sec"`
+	Status       string        `json:"status"`
+}
+
+// EmbedResult 为嵌入模型的性能测试结果
+type EmbedResult struct {
+	// Dimension 为返回向量的维度
+	Dimension int          `json:"dimension"`
+	Batches   []EmbedBatch `json:"batches"`
+}
+
+// RunEmbed 对嵌入模型依次测试每个批量大小,每个批量大小在预热后正式测量 Runs 次.
+// 所有请求之间保持模型加载,结束后卸载.至少一个批量大小测量成功时状态为完成.
+func (b *Benchmarker) RunEmbed(ctx context.Context, ip string, model string) Result {
+	defer b.unload(context.WithoutCancel(ctx), ip, model)
+
+	var res EmbedResult
+	// 首次请求包含模型加载时间,至少预热一次
+	for range max(b.Config.WarmupRuns, 1) {
+		b.embedOnce(ctx, ip, model, 1)
+	}
+	for _, size := range b.Config.EmbedBatchSizes {
+		batch := EmbedBatch{BatchSize: size}
+		var latencies []float64
+		for range b.Config.Runs {
+			latency, dim, status := b.embedOnce(ctx, ip, model, size)
+			if status != "完成" {
+				batch.Failed++
+				batch.Status = status
+				if ctx.Err() != nil {
+					break
+				}
+				continue
+			}
+			latencies = append(latencies, float64(latency))
+			res.Dimension = dim
+		}
+		batch.Runs = len(latencies)
+		if batch.Runs > 0 {
+			slices.Sort(latencies)
+			batch.Latency = time.Duration(percentile(latencies, 50))
+			batch.InputsPerSec = float64(size) / batch.Latency.Seconds()
+			batch.Status = "完成"
+		}
+		res.Batches = append(res.Batches, batch)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	status := "已取消"
+	for _, batch := range res.Batches {
+		if batch.Status == "完成" {
+			status = "完成"
+			break
+		}
+		status = batch.Status
+	}
+	return Result{Status: status, Embedding: &res}
+}
+
+// embedOnce 发送一次包含 size 个输入的 /api/embed 请求,返回耗时、向量维度和状态
+func (b *Benchmarker) embedOnce(ctx context.Context, ip string, model string, size int) (time.Duration, int, string) {
+	if err := b.Prober.Limiter.Wait(ctx, ip); err != nil {
+		return 0, 0, "已取消"
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.Config.Timeout)
+	defer cancel()
+
+	input := make([]string, size)
+	for i := range input {
+		// 每个输入略有不同,避免服务端缓存相同的输入
+		input[i] = fmt.Sprintf("%s (%d)", b.Config.Prompt, i+1)
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      model,
+		"input":      input,
+		"keep_alive": planKeepAlive,
+	})
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		b.Prober.BaseURL(ip)+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, "请求构造失败"
+	}
+	resp, err := b.Prober.Client.Do(req)
+	if err != nil {
+		return 0, 0, failureStatus(ctx, "连接失败")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Sprintf("HTTP错误: %d", resp.StatusCode)
+	}
+
+	var data struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return 0, 0, failureStatus(ctx, "响应格式错误")
+	}
+	latency := time.Since(start)
+	if len(data.Embeddings) != size || len(data.Embeddings[0]) == 0 {
+		return 0, 0, "响应不完整"
+	}
+	return latency, len(data.Embeddings[0]), "完成"
+}
@@ -0,0 +1,166 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/scope"
+)
+
+const (
+	DefaultMaxConcurrency = 8
+	MaxConcurrency        = 64 // 压测的最大并发请求数
+	DefaultLoadRounds     = 2
+	MaxLoadRounds         = 20
+	DefaultErrorThreshold = 0.2
+)
+
+// LoadConfig 为并发压测配置,并发数按 1、2、4 … MaxConcurrency 逐级增加
+type LoadConfig struct {
+	Model          string
+	MaxConcurrency int
+	// Rounds 为每个级别中每个并发用户依次发送的请求数
+	Rounds int
+	// ErrorThreshold 为错误率上限,某一级别超过该值时不再继续加压
+	ErrorThreshold float64
+}
+
+// Level 为一个并发级别的压测结果,延迟分位数只统计成功的请求
+type Level struct {
+	Concurrency int           `json:"concurrency"`
+	Requests    int           `json:"requests"`
+	Errors      int           `json:"errors"`
+	ErrorRate   float64       `json:"error_rate"`
+	Duration    time.Duration `json:"duration_ns"`
+	// TokensPerSec 为该级别所有成功请求生成的 token 总数除以级别总耗时
+	TokensPerSec  float64        `json:"tokens_per_sec"`
+	LatencyP50    time.Duration  `json:"latency_p50_ns"`
+	LatencyP90    time.Duration  `json:"latency_p90_ns"`
+	LatencyP99    time.Duration  `json:"latency_p99_ns"`
+	FirstTokenP50 time.Duration  `json:"first_token_p50_ns"`
+	FirstTokenP90 time.Duration  `json:"first_token_p90_ns"`
+	FirstTokenP99 time.Duration  `json:"first_token_p99_ns"`
+	ErrorStatuses map[string]int `json:"error_statuses,omitempty"`
+}
+
+// LoadReport 为单个端点的压测报告
+type LoadReport struct {
+	Endpoint  string    `json:"endpoint"`
+	Model     string    `json:"model"`
+	StartedAt time.Time `json:"started_at"`
+	Levels    []Level   `json:"levels"`
+	// Stopped 为提前停止加压的原因,完成全部级别时为空
+	Stopped string `json:"stopped,omitempty"`
+}
+
+// ConcurrencyLevels 返回 1、2、4 … 直到 max 的并发级别,max 不是 2 的幂时最后一级为 max
+func ConcurrencyLevels(max int) []int {
+	var levels []int
+	for c := 1; c < max; c *= 2 {
+		levels = append(levels, c)
+	}
+	return append(levels, max)
+}
+
+// LoadTest 对自有端点上的单个模型逐级增加并发流式请求,每完成一个级别调用一次 report.
+// 压测期间保持模型加载,结束后卸载.
+func (b *Benchmarker) LoadTest(ctx context.Context, ip string, cfg LoadConfig, report func(Level)) (LoadReport, error) {
+	if !scope.Match(b.Config.Owned, ip) {
+		return LoadReport{}, fmt.Errorf("%s 不在自有端点列表中,不能执行压测", ip)
+	}
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = DefaultMaxConcurrency
+	}
+	cfg.MaxConcurrency = min(cfg.MaxConcurrency, MaxConcurrency)
+	if cfg.Rounds <= 0 {
+		cfg.Rounds = DefaultLoadRounds
+	}
+	cfg.Rounds = min(cfg.Rounds, MaxLoadRounds)
+	if cfg.ErrorThreshold <= 0 {
+		cfg.ErrorThreshold = DefaultErrorThreshold
+	}
+
+	rep := LoadReport{Endpoint: b.Prober.BaseURL(ip), Model: cfg.Model, StartedAt: time.Now()}
+	defer b.unload(context.WithoutCancel(ctx), ip, cfg.Model)
+	for _, c := range ConcurrencyLevels(cfg.MaxConcurrency) {
+		level := b.runLevel(ctx, ip, cfg.Model, c, cfg.Rounds)
+		if ctx.Err() != nil {
+			rep.Stopped = "已取消"
+			break
+		}
+		rep.Levels = append(rep.Levels, level)
+		if report != nil {
+			report(level)
+		}
+		if level.ErrorRate > cfg.ErrorThreshold {
+			rep.Stopped = fmt.Sprintf("并发 %d 时错误率 %.0f%% 超过上限 %.0f%%",
+				c, level.ErrorRate*100, cfg.ErrorThreshold*100)
+			break
+		}
+	}
+	return rep, nil
+}
+
+// runLevel 以 concurrency 个并发用户各依次发送 rounds 个请求
+func (b *Benchmarker) runLevel(ctx context.Context, ip string, model string, concurrency int, rounds int) Level {
+	var (
+		mu      sync.Mutex
+		samples []Sample
+		wg      sync.WaitGroup
+	)
+	start := time.Now()
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				s := b.runOnce(ctx, ip, model, PhaseLoad, planKeepAlive)
+				mu.Lock()
+				samples = append(samples, s)
+				mu.Unlock()
+				if ctx.Err() != nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	level := Level{Concurrency: concurrency, Requests: len(samples), Duration: time.Since(start)}
+	var (
+		latency []float64
+		ttft    []float64
+		tokens  int
+	)
+	for _, s := range samples {
+		if !s.OK() {
+			level.Errors++
+			if level.ErrorStatuses == nil {
+				level.ErrorStatuses = map[string]int{}
+			}
+			level.ErrorStatuses[s.Status]++
+			continue
+		}
+		latency = append(latency, float64(s.Duration))
+		ttft = append(ttft, float64(s.FirstTokenDelay))
+		tokens += s.Tokens
+	}
+	if level.Requests > 0 {
+		level.ErrorRate = float64(level.Errors) / float64(level.Requests)
+	}
+	level.TokensPerSec = float64(tokens) / level.Duration.Seconds()
+	if len(latency) > 0 {
+		slices.Sort(latency)
+		slices.Sort(ttft)
+		level.LatencyP50 = time.Duration(percentile(latency, 50))
+		level.LatencyP90 = time.Duration(percentile(latency, 90))
+		level.LatencyP99 = time.Duration(percentile(latency, 99))
+		level.FirstTokenP50 = time.Duration(percentile(ttft, 50))
+		level.FirstTokenP90 = time.Duration(percentile(ttft, 90))
+		level.FirstTokenP99 = time.Duration(percentile(ttft, 99))
+	}
+	return level
+}
@@ -0,0 +1,238 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultSmokeSeed       = 42
+	DefaultSmokeNumPredict = 64
+	MaxSmokeTests          = 50
+	maxSmokeResponse       = 200 // 结果中保留的回复长度(字符数)
+)
+
+// SmokeTest 为一个冒烟测试用例,回复需要包含 Contains 或匹配 Regex
+type SmokeTest struct {
+	Name     string `yaml:"name" json:"name"`
+	Prompt   string `yaml:"prompt" json:"prompt"`
+	Contains string `yaml:"contains" json:"contains,omitempty"`
+	Regex    string `yaml:"regex" json:"regex,omitempty"`
+	// Models 为用例适用的模型名称子串,为空时适用于所有生成模型
+	Models []string `yaml:"models" json:"models,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// SmokeSuite 为冒烟测试文件,所有用例使用固定的 seed、temperature 0 和 num_predict,
+// 使同一模型的回复可以复现.文件中未设置 seed 时使用 DefaultSmokeSeed,设置为 0 时使用 0.
+type SmokeSuite struct {
+	Seed       int         `yaml:"seed"`
+	NumPredict int         `yaml:"num_predict"`
+	Tests      []SmokeTest `yaml:"tests"`
+}
+
+// LoadSmokeTests 读取并校验 YAML 格式的冒烟测试文件,所有问题一起返回
+func LoadSmokeTests(path string) (SmokeSuite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SmokeSuite{}, err
+	}
+	// 先填入默认 seed,文件中出现 seed 时才覆盖,以区分未设置和 0
+	suite := SmokeSuite{Seed: DefaultSmokeSeed}
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return SmokeSuite{}, fmt.Errorf("解析冒烟测试文件 %s 失败: %w", path, err)
+	}
+	if suite.NumPredict <= 0 {
+		suite.NumPredict = DefaultSmokeNumPredict
+	}
+	suite.NumPredict = min(suite.NumPredict, MaxNumPredict)
+
+	var problems []string
+	switch {
+	case len(suite.Tests) == 0:
+		problems = append(problems, "没有 tests")
+	case len(suite.Tests) > MaxSmokeTests:
+		problems = append(problems, fmt.Sprintf("有 %d 个用例,最多 %d 个", len(suite.Tests), MaxSmokeTests))
+	}
+	seen := map[string]bool{}
+	for i := range suite.Tests {
+		t := &suite.Tests[i]
+		if t.Name == "" {
+			t.Name = fmt.Sprintf("test-%d", i+1)
+		}
+		if seen[t.Name] {
+			problems = append(problems, fmt.Sprintf("用例名称重复: %s", t.Name))
+		}
+		seen[t.Name] = true
+		if strings.TrimSpace(t.Prompt) == "" {
+			problems = append(problems, fmt.Sprintf("用例 %s 的 prompt 为空", t.Name))
+		}
+		if (t.Contains == "") == (t.Regex == "") {
+			problems = append(problems, fmt.Sprintf("用例 %s 必须且只能设置 contains 或 regex 之一", t.Name))
+		}
+		if t.Regex != "" {
+			if t.re, err = regexp.Compile(t.Regex); err != nil {
+				problems = append(problems, fmt.Sprintf("用例 %s 的 regex 无效: %v", t.Name, err))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return SmokeSuite{}, fmt.Errorf("冒烟测试文件 %s 有误:\n%s", path, strings.Join(problems, "\n"))
+	}
+	return suite, nil
+}
+
+// appliesTo 判断用例是否适用于该模型
+func (t SmokeTest) appliesTo(model string) bool {
+	if len(t.Models) == 0 {
+		return true
+	}
+	for _, m := range t.Models {
+		if strings.Contains(model, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// match 判断回复是否符合预期
+func (t SmokeTest) match(response string) bool {
+	if t.re != nil {
+		return t.re.MatchString(response)
+	}
+	return strings.Contains(response, t.Contains)
+}
+
+// SmokeCheck 为单个用例的结果,Status 为通过、未通过或请求失败的原因
+type SmokeCheck struct {
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Status   string        `json:"status"`
+	Response string        `json:"response,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// SmokeResult 为单个模型的冒烟测试结果
+type SmokeResult struct {
+	Passed int          `json:"passed"`
+	Failed int          `json:"failed"`
+	Checks []SmokeCheck `json:"checks"`
+}
+
+// FailedNames 返回未通过的用例名称
+func (r SmokeResult) FailedNames() []string {
+	var names []string
+	for _, c := range r.Checks {
+		if !c.Passed {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// RunSmoke 依次执行适用于该模型的冒烟测试用例,没有适用的用例时返回 nil.
+// 用例之间保持模型加载,结束后卸载.
+func (b *Benchmarker) RunSmoke(ctx context.Context, ip string, model string) *SmokeResult {
+	suite := b.Config.Smoke
+	var tests []SmokeTest
+	for _, t := range suite.Tests {
+		if t.appliesTo(model) {
+			tests = append(tests, t)
+		}
+	}
+	if len(tests) == 0 {
+		return nil
+	}
+	defer b.unload(context.WithoutCancel(ctx), ip, model)
+
+	res := &SmokeResult{}
+	for _, t := range tests {
+		check := b.smokeOnce(ctx, ip, model, suite, t)
+		if check.Passed {
+			res.Passed++
+		} else {
+			res.Failed++
+		}
+		res.Checks = append(res.Checks, check)
+	}
+	return res
+}
+
+// thinkPattern 匹配推理模型回复中的思考过程,判断结果时只看最终回答
+var thinkPattern = regexp.MustCompile(`(?s)<think>.*?</think>`)
+
+// smokeOnce 以非流式请求执行一个用例
+func (b *Benchmarker) smokeOnce(ctx context.Context, ip string, model string, suite SmokeSuite, t SmokeTest) SmokeCheck {
+	check := SmokeCheck{Name: t.Name}
+	if err := b.Prober.Limiter.Wait(ctx, ip); err != nil {
+		check.Status = "已取消"
+		return check
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.Config.Timeout)
+	defer cancel()
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      model,
+		"prompt":     t.Prompt,
+		"stream":     false,
+		"keep_alive": planKeepAlive,
+		"options": map[string]interface{}{
+			"seed":        suite.Seed,
+			"temperature": 0,
+			"num_predict": suite.NumPredict,
+		},
+	})
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		b.Prober.BaseURL(ip)+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		check.Status = "请求构造失败"
+		return check
+	}
+	resp, err := b.Prober.Client.Do(req)
+	if err != nil {
+		check.Status = failureStatus(ctx, "连接失败")
+		return check
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		check.Status = fmt.Sprintf("HTTP错误: %d", resp.StatusCode)
+		return check
+	}
+	var data struct {
+		Response string `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		check.Status = failureStatus(ctx, "响应格式错误")
+		return check
+	}
+	check.Duration = time.Since(start)
+
+	answer := strings.TrimSpace(thinkPattern.ReplaceAllString(data.Response, ""))
+	check.Response = truncate(answer, maxSmokeResponse)
+	check.Passed = t.match(answer)
+	check.Status = "未通过"
+	if check.Passed {
+		check.Status = "通过"
+	}
+	return check
+}
+
+// truncate 按字符数截断字符串
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
@@ -0,0 +1,131 @@
+package bench
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// 样本所属的测试阶段
+const (
+	PhaseCold    = "cold"    // 卸载模型后的首次请求,包含模型加载时间
+	PhaseWarmup  = "warmup"  // 预热,不计入统计
+	PhaseMeasure = "measure" // 正式测量
+	PhaseLoad    = "load"    // 并发压测
+)
+
+// planKeepAlive 为多次测量之间保持模型加载的时长,足以覆盖相邻两次请求的间隔
+const planKeepAlive = "2m"
+
+// Sample 为一次生成请求的原始测量值
+type Sample struct {
+	Phase           string        `json:"phase"`
+	StartedAt       time.Time     `json:"started_at"`
+	FirstTokenDelay time.Duration `json:"first_token_delay_ns"`
+	TokensPerSec    float64       `json:"tokens_per_sec"`
+	Tokens          int           `json:"tokens"`
+	// Duration 为从发送请求到收到最后一个片段的时间,失败时为 0
+	Duration time.Duration `json:"duration_ns,omitempty"`
+	// LoadDuration 为服务端报告的模型加载时间,模型已加载时接近 0
+	LoadDuration time.Duration `json:"load_duration_ns,omitempty"`
+	Status       string        `json:"status"`
+}
+
+// OK 判断本次请求是否成功完成
+func (s Sample) OK() bool {
+	return s.Status == "完成"
+}
+
+// Stats 为正式测量的统计值,分位数按最近秩法计算,标准差为样本标准差
+type Stats struct {
+	Runs   int `json:"runs"`   // 成功的正式测量次数
+	Failed int `json:"failed"` // 失败的正式测量次数
+
+	FirstTokenP50    time.Duration `json:"first_token_p50_ns"`
+	FirstTokenP90    time.Duration `json:"first_token_p90_ns"`
+	FirstTokenP99    time.Duration `json:"first_token_p99_ns"`
+	FirstTokenStdDev time.Duration `json:"first_token_stddev_ns"`
+
+	TokensPerSecP50    float64 `json:"tokens_per_sec_p50"`
+	TokensPerSecP90    float64 `json:"tokens_per_sec_p90"`
+	TokensPerSecP99    float64 `json:"tokens_per_sec_p99"`
+	TokensPerSecStdDev float64 `json:"tokens_per_sec_stddev"`
+
+	// Cold 为冷启动测量,未启用冷启动测量时为空
+	Cold *Sample `json:"cold,omitempty"`
+}
+
+// summarize 汇总测试计划的样本.detailed 为 false 时只有一次请求,不附带统计值和样本.
+// 至少一次正式测量成功时状态为完成,否则使用最后一次失败的状态.
+func summarize(samples []Sample, detailed bool) Result {
+	var (
+		ttft   []float64
+		tps    []float64
+		stats  Stats
+		status string
+	)
+	for i := range samples {
+		s := samples[i]
+		switch {
+		case s.Phase == PhaseCold:
+			stats.Cold = &samples[i]
+		case s.Phase != PhaseMeasure:
+		case s.OK():
+			ttft = append(ttft, float64(s.FirstTokenDelay))
+			tps = append(tps, s.TokensPerSec)
+		default:
+			stats.Failed++
+			status = s.Status
+		}
+	}
+	stats.Runs = len(ttft)
+	if stats.Runs == 0 {
+		if status == "" {
+			status = "已取消"
+		}
+		if !detailed {
+			return Result{Status: status}
+		}
+		return Result{Status: status, Stats: &stats, Samples: samples}
+	}
+
+	slices.Sort(ttft)
+	slices.Sort(tps)
+	stats.FirstTokenP50 = time.Duration(percentile(ttft, 50))
+	stats.FirstTokenP90 = time.Duration(percentile(ttft, 90))
+	stats.FirstTokenP99 = time.Duration(percentile(ttft, 99))
+	stats.FirstTokenStdDev = time.Duration(stddev(ttft))
+	stats.TokensPerSecP50 = percentile(tps, 50)
+	stats.TokensPerSecP90 = percentile(tps, 90)
+	stats.TokensPerSecP99 = percentile(tps, 99)
+	stats.TokensPerSecStdDev = stddev(tps)
+
+	res := Result{FirstTokenDelay: stats.FirstTokenP50, TokensPerSec: stats.TokensPerSecP50, Status: "完成"}
+	if detailed {
+		res.Stats, res.Samples = &stats, samples
+	}
+	return res
+}
+
+// percentile 返回已排序数据的第 p 百分位数
+func percentile(sorted []float64, p float64) float64 {
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+// stddev 返回样本标准差,少于两个样本时为 0
+func stddev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sq / float64(len(values)-1))
+}
@@ -0,0 +1,107 @@
+package bench
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// seq 返回 1 到 n 的有序数据
+func seq(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = float64(i + 1)
+	}
+	return values
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []float64
+		p      float64
+		want   float64
+	}{
+		{"n=1 p50", []float64{7}, 50, 7},
+		{"n=1 p99", []float64{7}, 99, 7},
+		{"n=2 p50", []float64{1, 3}, 50, 1},
+		{"n=2 p51", []float64{1, 3}, 51, 3},
+		{"n=2 p99", []float64{1, 3}, 99, 3},
+		{"n=10 p50", seq(10), 50, 5},
+		{"n=10 p90", seq(10), 90, 9},
+		{"n=10 p95", seq(10), 95, 10},
+		{"n=10 p99", seq(10), 99, 10},
+		{"n=100 p50", seq(100), 50, 50},
+		{"n=100 p95", seq(100), 95, 95},
+		{"n=100 p99", seq(100), 99, 99},
+		{"n=100 p100", seq(100), 100, 100},
+		{"n=100 p0", seq(100), 0, 1},
+		{"n=20 p95", seq(20), 95, 19},
+		{"n=20 p99", seq(20), 99, 20},
+	}
+	for _, tt := range tests {
+		if got := percentile(tt.sorted, tt.p); got != tt.want {
+			t.Errorf("%s: percentile = %v, 期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStdDev(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{"n=0", nil, 0},
+		{"n=1", []float64{5}, 0},
+		{"n=2", []float64{1, 3}, math.Sqrt(2)},
+		{"相同值", []float64{4, 4, 4}, 0},
+		{"已知数据", []float64{2, 4, 4, 4, 5, 5, 7, 9}, math.Sqrt(32.0 / 7)},
+		{"1 到 10", seq(10), math.Sqrt(55.0 / 6)},
+	}
+	for _, tt := range tests {
+		if got := stddev(tt.values); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: stddev = %v, 期望 %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	ms := time.Millisecond
+	samples := []Sample{
+		{Phase: PhaseCold, FirstTokenDelay: 900 * ms, TokensPerSec: 5, Status: "完成"},
+		{Phase: PhaseWarmup, FirstTokenDelay: 500 * ms, TokensPerSec: 1, Status: "完成"},
+		{Phase: PhaseMeasure, FirstTokenDelay: 300 * ms, TokensPerSec: 30, Status: "完成"},
+		{Phase: PhaseMeasure, FirstTokenDelay: 100 * ms, TokensPerSec: 10, Status: "完成"},
+		{Phase: PhaseMeasure, Status: "超时"},
+		{Phase: PhaseMeasure, FirstTokenDelay: 200 * ms, TokensPerSec: 20, Status: "完成"},
+	}
+	res := summarize(samples, true)
+	s := res.Stats
+	if res.Status != "完成" || s == nil || s.Runs != 3 || s.Failed != 1 || s.Cold != &samples[0] {
+		t.Fatalf("Result = %+v, Stats = %+v", res, s)
+	}
+	// 预热和失败的样本不计入统计
+	if s.FirstTokenP50 != 200*ms || s.FirstTokenP90 != 300*ms || s.FirstTokenP99 != 300*ms || s.FirstTokenStdDev != 100*ms {
+		t.Errorf("首 token 延迟统计 = %v %v %v %v", s.FirstTokenP50, s.FirstTokenP90, s.FirstTokenP99, s.FirstTokenStdDev)
+	}
+	if s.TokensPerSecP50 != 20 || s.TokensPerSecP99 != 30 || s.TokensPerSecStdDev != 10 {
+		t.Errorf("生成速度统计 = %v %v %v", s.TokensPerSecP50, s.TokensPerSecP99, s.TokensPerSecStdDev)
+	}
+	if res.FirstTokenDelay != s.FirstTokenP50 || res.TokensPerSec != s.TokensPerSecP50 {
+		t.Errorf("结果应为中位数: %+v", res)
+	}
+
+	// 单次请求不附带统计值
+	if res := summarize(samples[3:4], false); res.Stats != nil || res.Samples != nil || res.TokensPerSec != 10 {
+		t.Errorf("单次请求 Result = %+v", res)
+	}
+	// 全部失败时使用最后一次失败的状态
+	failed := []Sample{{Phase: PhaseMeasure, Status: "连接失败"}, {Phase: PhaseMeasure, Status: "超时"}}
+	if res := summarize(failed, true); res.Status != "超时" || res.Stats.Runs != 0 || res.Stats.Failed != 2 {
+		t.Errorf("全部失败 Result = %+v", res)
+	}
+	if res := summarize(nil, false); res.Status != "已取消" {
+		t.Errorf("没有样本 Status = %q", res.Status)
+	}
+}
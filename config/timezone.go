@@ -31,6 +31,17 @@ func Now() time.Time {
 	return time.Now().In(location)
 }
 
+// Location 返回配置的时区,未初始化时返回默认时区
+func Location() *time.Location {
+	if location == nil {
+		if loc, err := time.LoadLocation(defaultTimeZone); err == nil {
+			return loc
+		}
+		return time.Local
+	}
+	return location
+}
+
 // 格式化时间
 func FormatTime(t time.Time, layout string) string {
 	return t.In(location).Format(layout)
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // RequiredVars 定义必需的环境变量
@@ -36,3 +39,106 @@ func ValidateEnv() error {
 
 	return nil
 }
+
+// FieldError 描述单个配置项的问题
+type FieldError struct {
+	Key     string
+	Source  string
+	Message string
+}
+
+// ValidationError 汇总一次校验中发现的所有配置问题
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "配置校验失败，共 %d 个问题:", len(e))
+	for _, fe := range e {
+		fmt.Fprintf(&b, "\n  - %s (%s): %s", fe.Key, fe.Source, fe.Message)
+	}
+	return b.String()
+}
+
+// flagSources 记录被命令行参数覆盖的配置项
+var flagSources = map[string]string{}
+
+// SetFromFlag 用命令行参数的值覆盖环境变量,并记录配置来源
+func SetFromFlag(key string, flagName string, value string) {
+	os.Setenv(key, value)
+	flagSources[key] = "命令行参数 -" + flagName
+}
+
+// Source 返回配置项的来源:命令行参数、环境变量或默认值
+func Source(key string) string {
+	if s, ok := flagSources[key]; ok {
+		return s
+	}
+	if _, ok := os.LookupEnv(key); ok {
+		return "环境变量"
+	}
+	return "默认值"
+}
+
+// Validator 收集配置问题,所有问题在 Err 中一次性返回
+type Validator struct {
+	errs ValidationError
+}
+
+// Errorf 记录一个配置项的问题
+func (v *Validator) Errorf(key string, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Key: key, Source: Source(key), Message: fmt.Sprintf(format, args...)})
+}
+
+// Int 读取整数配置,设置了但无法解析时记录问题并返回默认值
+func (v *Validator) Int(key string, defaultVal int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultVal
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		v.Errorf(key, "不是有效的整数: %q", value)
+		return defaultVal
+	}
+	return n
+}
+
+// Float 读取浮点数配置,设置了但无法解析时记录问题并返回默认值
+func (v *Validator) Float(key string, defaultVal float64) float64 {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultVal
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		v.Errorf(key, "不是有效的数字: %q", value)
+		return defaultVal
+	}
+	return f
+}
+
+// Duration 读取时长配置,设置了但无法解析或不为正数时记录问题并返回默认值
+func (v *Validator) Duration(key string, defaultVal time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		v.Errorf(key, "不是有效的时长(例如 30s、2m): %q", value)
+		return defaultVal
+	}
+	if d <= 0 {
+		v.Errorf(key, "时长必须为正数: %q", value)
+		return defaultVal
+	}
+	return d
+}
+
+// Err 没有问题时返回 nil,否则返回 ValidationError
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
@@ -0,0 +1,101 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultStoreDir     = "data"
+	DefaultDrainTimeout = 5 * time.Minute
+)
+
+// Job 为 config.yml 中定义的一个定时扫描任务.未设置的字段沿用环境变量中的扫描配置.
+type Job struct {
+	Name     string `yaml:"name"`
+	Schedule string `yaml:"schedule"`
+	// InputFile 为本任务的扫描目标文件
+	InputFile string `yaml:"input_file"`
+	// ImportFile 为本任务导入的 Nmap XML、CSV 或 JSON 清单,设置后跳过目标发现
+	ImportFile  string `yaml:"import_file"`
+	ScannerType string `yaml:"scanner_type"`
+	// ModelFilter 为空指针时沿用 MODEL_FILTER,设置为空字符串表示保留全部模型
+	ModelFilter  *string `yaml:"model_filter"`
+	DisableBench *bool   `yaml:"disable_bench"`
+
+	schedule Schedule
+}
+
+// Config 为 config.yml 中的 daemon 配置段
+type Config struct {
+	// StoreDir 为运行记录和结果的保存目录
+	StoreDir string `yaml:"store_dir"`
+	// DrainTimeout 为收到 SIGTERM 后等待进行中任务结束的最长时间,超时后取消任务
+	DrainTimeout time.Duration `yaml:"drain_timeout"`
+	Jobs         []Job         `yaml:"jobs"`
+}
+
+// jobNamePattern 限制任务名称,任务名称会出现在结果文件名中
+var jobNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// LoadConfig 读取 config.yml 中的 daemon 配置段,并校验全部任务,所有问题一次性返回
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	var file struct {
+		Daemon Config `yaml:"daemon"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return Config{}, fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+	cfg := file.Daemon
+	if cfg.StoreDir == "" {
+		cfg.StoreDir = DefaultStoreDir
+	}
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = DefaultDrainTimeout
+	}
+
+	var errs []error
+	seen := map[string]bool{}
+	for i := range cfg.Jobs {
+		job := &cfg.Jobs[i]
+		switch {
+		case !jobNamePattern.MatchString(job.Name):
+			errs = append(errs, fmt.Errorf("第 %d 个任务的名称 %q 无效,只能包含字母、数字、- 和 _", i+1, job.Name))
+		case seen[job.Name]:
+			errs = append(errs, fmt.Errorf("任务名称重复: %s", job.Name))
+		}
+		seen[job.Name] = true
+
+		if job.schedule, err = ParseSchedule(job.Schedule); err != nil {
+			errs = append(errs, fmt.Errorf("任务 %s 的 schedule 无效: %w", job.Name, err))
+		}
+		switch job.ScannerType {
+		case "", "zmap", "masscan", "native":
+		default:
+			errs = append(errs, fmt.Errorf("任务 %s 的 scanner_type 不支持: %q", job.Name, job.ScannerType))
+		}
+		if job.InputFile != "" {
+			if _, err := os.Stat(job.InputFile); err != nil {
+				errs = append(errs, fmt.Errorf("任务 %s 的 input_file 不可用: %w", job.Name, err))
+			}
+		}
+		if job.ImportFile != "" {
+			if _, err := os.Stat(job.ImportFile); err != nil {
+				errs = append(errs, fmt.Errorf("任务 %s 的 import_file 不可用: %w", job.Name, err))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, fmt.Errorf("配置文件 %s 中的 daemon 配置有误:\n%w", path, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,133 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+// bits 返回包含给定取值的位图
+func bits(values ...int) uint64 {
+	var b uint64
+	for _, v := range values {
+		b |= 1 << uint(v)
+	}
+	return b
+}
+
+func TestParseField(t *testing.T) {
+	tests := []struct {
+		field    string
+		min, max int
+		names    map[string]int
+		want     uint64
+	}{
+		{"*", 0, 5, nil, bits(0, 1, 2, 3, 4, 5)},
+		{"3", 0, 59, nil, bits(3)},
+		{"1,5,7", 0, 59, nil, bits(1, 5, 7)},
+		{"10-13", 0, 59, nil, bits(10, 11, 12, 13)},
+		{"*/15", 0, 59, nil, bits(0, 15, 30, 45)},
+		{"10-20/5", 0, 59, nil, bits(10, 15, 20)},
+		{"50/4", 0, 59, nil, bits(50, 54, 58)},
+		{"1-3,20-22/2", 0, 23, nil, bits(1, 2, 3, 20, 22)},
+		{"jan,MAR,dec", 1, 12, monthNames, bits(1, 3, 12)},
+		{"mon-fri", 0, 7, dayNames, bits(1, 2, 3, 4, 5)},
+		{"sat-7", 0, 7, dayNames, bits(6, 7)},
+	}
+	for _, tt := range tests {
+		got, err := parseField(tt.field, tt.min, tt.max, tt.names)
+		if err != nil {
+			t.Errorf("parseField(%q) 返回错误: %v", tt.field, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseField(%q) = %b, 期望 %b", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"1,,2 * * * *",
+		"@never",
+		"@every 500ms",
+		"@every soon",
+	}
+	for _, spec := range tests {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) 期望返回错误", spec)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// 2024-03-15 为周五
+	from := time.Date(2024, 3, 15, 10, 30, 45, 0, time.UTC)
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"* * * * *", from, time.Date(2024, 3, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", from, time.Date(2024, 3, 15, 10, 45, 0, 0, time.UTC)},
+		{"30 10 * * *", from, time.Date(2024, 3, 16, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", from, time.Date(2024, 3, 15, 13, 0, 0, 0, time.UTC)},
+		{"@hourly", from, time.Date(2024, 3, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", from, time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", from, time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", from, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", from, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * mon-wed", from, time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", from, time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 feb *", from, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		// 日期和星期都有限制时满足其一即可:16 日与下一个周一中较早者
+		{"0 0 16 * 1", from, time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * 1", from, time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)},
+		// 日期有限制而星期为 * 时只看日期
+		{"0 0 31 * *", from, time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)},
+		// 跳过没有 31 日的月份和非闰年
+		{"0 0 31 * *", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", from, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// 跨年
+		{"59 23 31 12 *", time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC), time.Date(2025, 12, 31, 23, 59, 0, 0, time.UTC)},
+		// 没有可用时间时返回零值
+		{"0 0 30 2 *", from, time.Time{}},
+		{"@every 90s", from, from.Add(90 * time.Second)},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) 返回错误: %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%v) = %v, 期望 %v", tt.spec, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleNextLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	s, err := ParseSchedule("0 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.Next(time.Date(2024, 3, 15, 3, 0, 0, 0, loc))
+	want := time.Date(2024, 3, 16, 2, 0, 0, 0, loc)
+	if !got.Equal(want) || got.Location() != loc {
+		t.Fatalf("Next = %v, 期望 %v", got, want)
+	}
+}
@@ -0,0 +1,195 @@
+// Package daemon 按 cron 表达式定期执行扫描任务,同一任务不会重叠运行,
+// 每次运行的记录和结果写入结果存储.
+package daemon
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/logging"
+	"github.com/aspnmy/ollama_scanner/sink"
+	"github.com/aspnmy/ollama_scanner/store"
+)
+
+// RunFunc 执行一次扫描任务,结果写入 out.ctx 被取消时应尽快返回.
+type RunFunc func(ctx context.Context, job Job, out sink.Sink) error
+
+// Daemon 为定时扫描调度器
+type Daemon struct {
+	store *store.Store
+	run   RunFunc
+	log   *slog.Logger
+	loc   *time.Location
+
+	// jobCtx 与调度循环的 ctx 分开,停止调度后进行中的任务仍可继续运行直到排空
+	jobCtx    context.Context
+	cancelJob context.CancelFunc
+	reload    chan struct{}
+	wg        sync.WaitGroup
+
+	mu      sync.Mutex
+	cfg     Config
+	running map[string]bool
+}
+
+// New 创建调度器,logger 为空时不输出日志,loc 为空时使用本地时区
+func New(cfg Config, st *store.Store, run RunFunc, logger *slog.Logger, loc *time.Location) *Daemon {
+	if logger == nil {
+		logger = logging.Discard()
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	jobCtx, cancel := context.WithCancel(context.Background())
+	return &Daemon{
+		store:     st,
+		run:       run,
+		log:       logger,
+		loc:       loc,
+		jobCtx:    jobCtx,
+		cancelJob: cancel,
+		reload:    make(chan struct{}, 1),
+		cfg:       cfg,
+		running:   map[string]bool{},
+	}
+}
+
+// Reload 替换任务配置,进行中的任务不受影响,新的调度从下一次触发时间开始生效
+func (d *Daemon) Reload(cfg Config) {
+	d.mu.Lock()
+	d.cfg = cfg
+	d.mu.Unlock()
+	select {
+	case d.reload <- struct{}{}:
+	default:
+	}
+}
+
+// Jobs 返回当前的任务配置
+func (d *Daemon) Jobs() []Job {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cfg.Jobs
+}
+
+// Run 运行调度循环直到 ctx 被取消.返回时进行中的任务仍在运行,需要调用 Drain 等待.
+func (d *Daemon) Run(ctx context.Context) error {
+	base := time.Now().In(d.loc)
+	for {
+		jobs := d.Jobs()
+		var next time.Time
+		var due []Job
+		for _, job := range jobs {
+			t := job.schedule.Next(base)
+			switch {
+			case t.IsZero():
+			case next.IsZero() || t.Before(next):
+				next, due = t, []Job{job}
+			case t.Equal(next):
+				due = append(due, job)
+			}
+		}
+
+		// 没有可调度的任务时 wake 为 nil,只等待重新加载或退出
+		var wake <-chan time.Time
+		if !next.IsZero() {
+			d.log.Info("等待下一次调度", "at", next.Format(time.RFC3339), "jobs", len(due))
+			timer := time.NewTimer(time.Until(next))
+			wake = timer.C
+			// Go 1.23 起未停止的计时器不再被引用后即可回收
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-d.reload:
+			base = time.Now().In(d.loc)
+		case <-wake:
+			for _, job := range due {
+				d.start(job, next)
+			}
+			base = next
+		}
+	}
+}
+
+// start 在后台启动一次任务,同一任务的上一次运行尚未结束时跳过并记录
+func (d *Daemon) start(job Job, at time.Time) {
+	d.mu.Lock()
+	if d.running[job.Name] {
+		d.mu.Unlock()
+		d.log.Warn("上一次运行尚未结束，跳过本次调度", "job", job.Name)
+		if err := d.store.Skip(job.Name, at, "上一次运行尚未结束"); err != nil {
+			d.log.Error("写入运行记录失败", "job", job.Name, "error", err)
+		}
+		return
+	}
+	d.running[job.Name] = true
+	d.wg.Add(1)
+	d.mu.Unlock()
+
+	go func() {
+		defer d.wg.Done()
+		defer func() {
+			d.mu.Lock()
+			delete(d.running, job.Name)
+			d.mu.Unlock()
+		}()
+		d.execute(job)
+	}()
+}
+
+// execute 执行任务并把结果和最终状态写入结果存储
+func (d *Daemon) execute(job Job) {
+	rec, err := d.store.Begin(job.Name, time.Now().In(d.loc))
+	if err != nil {
+		d.log.Error("创建运行记录失败", "job", job.Name, "error", err)
+		return
+	}
+	log := d.log.With("job", job.Name, "run", rec.ID())
+	log.Info("开始执行任务")
+
+	err = d.run(d.jobCtx, job, rec)
+	if cerr := rec.Close(); cerr != nil {
+		err = errors.Join(err, cerr)
+	}
+	status := store.RunSucceeded
+	switch {
+	case d.jobCtx.Err() != nil:
+		status = store.RunCancelled
+	case err != nil:
+		status = store.RunFailed
+	}
+	if ferr := rec.Finish(status, err, time.Now().In(d.loc)); ferr != nil {
+		log.Error("更新运行记录失败", "error", ferr)
+	}
+	if err != nil {
+		log.Warn("任务结束", "status", status, "error", err)
+	} else {
+		log.Info("任务结束", "status", status)
+	}
+}
+
+// Drain 等待进行中的任务结束,超过 timeout 后取消剩余任务并等待它们退出
+func (d *Daemon) Drain(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return
+	case <-time.After(timeout):
+		d.log.Warn("等待任务结束超时，取消进行中的任务", "timeout", timeout)
+	}
+	d.cancelJob()
+	<-done
+}
+
+// Cancel 立即取消所有进行中的任务
+func (d *Daemon) Cancel() {
+	d.cancelJob()
+}
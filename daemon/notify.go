@@ -0,0 +1,26 @@
+package daemon
+
+import (
+	"net"
+	"os"
+)
+
+// Notify 按 sd_notify 协议向 systemd 发送状态,例如 READY=1、RELOADING=1、STOPPING=1.
+// 未由 systemd 以 Type=notify 启动时(NOTIFY_SOCKET 为空)什么也不做.
+func Notify(state string) error {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return nil
+	}
+	// 以 @ 开头的是抽象命名空间套接字
+	if path[0] == '@' {
+		path = "\x00" + path[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
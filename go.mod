@@ -3,3 +3,5 @@ module github.com/aspnmy/ollama_scanner
 go 1.24.0
 
 require github.com/aspnmy/ollama_scanner_envmanager v0.0.2
+
+require gopkg.in/yaml.v3 v3.0.1
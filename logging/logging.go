@@ -0,0 +1,91 @@
+// Package logging 根据配置创建结构化日志记录器,支持文本或 JSON 格式,
+// 并可写入按大小轮转的日志文件,与面向用户的进度显示分开.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const (
+	DefaultMaxSizeMB  = 50
+	DefaultMaxBackups = 5
+)
+
+// Config 为日志配置
+type Config struct {
+	Enabled bool
+	// Level 为 debug、info、warn 或 error
+	Level string
+	// Format 为 text 或 json
+	Format string
+	// Path 为空时写入标准错误输出
+	Path       string
+	MaxSizeMB  int
+	MaxBackups int
+}
+
+// New 创建日志记录器,返回的 io.Closer 用于关闭日志文件
+func New(cfg Config) (*slog.Logger, io.Closer, error) {
+	if !cfg.Enabled {
+		return Discard(), io.NopCloser(nil), nil
+	}
+
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		w      io.Writer = os.Stderr
+		closer io.Closer = io.NopCloser(nil)
+	)
+	if cfg.Path != "" {
+		maxSize := cfg.MaxSizeMB
+		if maxSize <= 0 {
+			maxSize = DefaultMaxSizeMB
+		}
+		backups := cfg.MaxBackups
+		if backups < 0 {
+			backups = DefaultMaxBackups
+		}
+		file, err := NewRotatingFile(cfg.Path, int64(maxSize)<<20, backups)
+		if err != nil {
+			return nil, nil, err
+		}
+		w, closer = file, file
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(w, opts)
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		closer.Close()
+		return nil, nil, fmt.Errorf("不支持的日志格式: %s", cfg.Format)
+	}
+	return slog.New(handler), closer, nil
+}
+
+// ParseLevel 解析日志级别,空字符串视为 info
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("不支持的日志级别: %s", s)
+	}
+	return level, nil
+}
+
+// Discard 返回丢弃所有日志的记录器
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RotatingFile 为按大小轮转的日志文件,超过上限时将当前文件重命名为 .1,
+// 已有的备份依次后移,超过 maxBackups 的最旧备份被删除
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	// file 为 nil 时日志文件无法重新打开,日志写入 fallback
+	file     *os.File
+	fallback io.Writer
+	size     int64
+}
+
+// NewRotatingFile 打开日志文件,必要时创建所在目录
+func NewRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %w", err)
+	}
+	r := &RotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups, fallback: os.Stderr}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("读取日志文件信息失败: %w", err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		r.rotate()
+	}
+	var (
+		n   int
+		err error
+	)
+	if r.file != nil {
+		n, err = r.file.Write(p)
+	} else {
+		n, err = r.fallback.Write(p)
+	}
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate 关闭当前文件,依次重命名备份后重新打开.重命名失败时继续写入原文件;
+// 重新打开失败时改为写入标准错误输出并报告原因,再写满 maxSize 后重试
+func (r *RotatingFile) rotate() {
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	var renameErr error
+	if r.maxBackups == 0 {
+		os.Remove(r.path)
+	} else {
+		os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxBackups))
+		for i := r.maxBackups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		if err := os.Rename(r.path, r.path+".1"); err != nil && !os.IsNotExist(err) {
+			renameErr = err
+		}
+	}
+	if err := r.open(); err != nil {
+		r.size = 0
+		fmt.Fprintf(r.fallback, "日志文件无法重新打开,日志改为写入标准错误输出: %v\n", err)
+		return
+	}
+	if renameErr != nil {
+		// 避免每次写入都重新尝试轮转
+		r.size = 0
+		fmt.Fprintf(r.fallback, "轮转日志文件失败,继续写入 %s: %v\n", r.path, renameErr)
+	}
+}
+
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
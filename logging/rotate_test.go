@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFileRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.log")
+	r, err := NewRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	for name, want := range map[string]string{
+		path:        "dddddddd\n",
+		path + ".1": "cccccccc\n",
+		path + ".2": "bbbbbbbb\n",
+	} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, 期望 %q", filepath.Base(name), data, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("超过 maxBackups 的备份未删除: %v", err)
+	}
+}
+
+func TestRotatingFileFallback(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	path := filepath.Join(dir, "scan.log")
+	r, err := NewRotatingFile(path, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var fallback bytes.Buffer
+	r.fallback = &fallback
+	if _, err := r.Write([]byte("aaaaaaaa\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 日志目录被替换为普通文件,轮转后无法重新打开
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]byte("bbbbbbbb\n")); err != nil {
+		t.Fatalf("回退后写入失败: %v", err)
+	}
+	if r.file != nil {
+		t.Fatal("重新打开失败后仍持有文件")
+	}
+	out := fallback.String()
+	if !strings.Contains(out, "无法重新打开") || !strings.HasSuffix(out, "bbbbbbbb\n") {
+		t.Fatalf("回退输出 = %q", out)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
@@ -0,0 +1,549 @@
+// Package mock 提供模拟的 Ollama 服务,用于在不接触真实服务器的情况下演练扫描,
+// 以及测试探测和性能测试代码.Handler 可以直接配合 httptest.NewServer 使用.
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aspnmy/ollama_scanner/probe"
+)
+
+// Failure 为模拟服务的故障模式
+type Failure string
+
+const (
+	FailureNone          Failure = ""
+	FailureSlowHeaders   Failure = "slow-headers"   // 长时间不发送响应头
+	FailureMalformedJSON Failure = "malformed-json" // 返回无法解析的 JSON
+	FailureEndlessStream Failure = "endless-stream" // 生成接口持续输出且永不结束
+)
+
+// ParseFailure 解析故障模式名称,空字符串和 none 表示正常
+func ParseFailure(s string) (Failure, error) {
+	switch f := Failure(strings.ToLower(strings.TrimSpace(s))); f {
+	case FailureNone, "none":
+		return FailureNone, nil
+	case FailureSlowHeaders, FailureMalformedJSON, FailureEndlessStream:
+		return f, nil
+	default:
+		return "", fmt.Errorf("不支持的故障模式: %s", s)
+	}
+}
+
+const (
+	DefaultVersion         = "0.6.5"
+	DefaultTokensPerSec    = 50
+	DefaultSlowHeaderDelay = time.Minute
+	defaultNumPredict      = 32
+)
+
+// 名称中包含 embed 的模型被视为嵌入模型
+const (
+	EmbeddingFamily    = "nomic-bert"
+	EmbeddingDimension = 768
+)
+
+// DefaultModels 为未指定模型时提供的模型列表
+var DefaultModels = []string{"deepseek-r1:1.5b", "deepseek-r1:7b", "qwen2.5:0.5b"}
+
+// Model 为模拟服务提供的单个模型
+type Model struct {
+	Name    string
+	Size    int64
+	License string
+	// Running 为真时出现在 /api/ps 中
+	Running bool
+	// Embedding 为真时为嵌入模型,只支持 /api/embed
+	Embedding bool
+}
+
+// NewModel 根据模型标签构造模型,大小按参数规模粗略估算
+func NewModel(name string) Model {
+	size := int64(probe.ParseModelSize(name) * 0.6 * (1 << 30))
+	if size <= 0 {
+		size = 1 << 30
+	}
+	return Model{Name: name, Size: size, License: "MIT License", Embedding: strings.Contains(name, "embed")}
+}
+
+// Config 为单个模拟实例的行为配置,零值字段使用默认值
+type Config struct {
+	Models  []Model
+	Version string
+	// Latency 为每个请求在响应前的额外延迟
+	Latency time.Duration
+	// TokensPerSec 为 /api/generate 的输出速度
+	TokensPerSec float64
+	// Token 非空时要求请求携带 Authorization: Bearer <Token>
+	Token   string
+	Failure Failure
+	// SlowHeaderDelay 为 slow-headers 模式下发送响应头前的等待时间
+	SlowHeaderDelay time.Duration
+}
+
+func (c *Config) setDefaults() {
+	if c.Models == nil {
+		for i, name := range DefaultModels {
+			m := NewModel(name)
+			m.Running = i == 0
+			c.Models = append(c.Models, m)
+		}
+	}
+	if c.Version == "" {
+		c.Version = DefaultVersion
+	}
+	if c.TokensPerSec <= 0 {
+		c.TokensPerSec = DefaultTokensPerSec
+	}
+	if c.SlowHeaderDelay <= 0 {
+		c.SlowHeaderDelay = DefaultSlowHeaderDelay
+	}
+}
+
+// handler 实现 Ollama API 中扫描器用到的接口
+type handler struct {
+	cfg Config
+	mux *http.ServeMux
+}
+
+// Handler 返回按 cfg 行为响应的 Ollama API 处理器
+func Handler(cfg Config) http.Handler {
+	cfg.setDefaults()
+	h := &handler{cfg: cfg, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /{$}", h.root)
+	h.mux.HandleFunc("GET /api/version", h.version)
+	h.mux.HandleFunc("GET /api/tags", h.tags)
+	h.mux.HandleFunc("GET /api/ps", h.ps)
+	h.mux.HandleFunc("POST /api/show", h.show)
+	h.mux.HandleFunc("POST /api/generate", h.generate)
+	h.mux.HandleFunc("POST /api/embed", h.embed)
+	h.mux.HandleFunc("POST /api/chat", h.chat)
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.cfg.Failure == FailureSlowHeaders && !sleep(r.Context(), h.cfg.SlowHeaderDelay) {
+		return
+	}
+	if !sleep(r.Context(), h.cfg.Latency) {
+		return
+	}
+	if h.cfg.Token != "" && r.Header.Get("Authorization") != "Bearer "+h.cfg.Token {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"unauthorized"}`))
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// sleep 等待 d,请求被取消时返回 false
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// writeJSON 输出 JSON 响应,malformed-json 模式下输出被截断的内容
+func (h *handler) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	data, _ := json.Marshal(v)
+	if h.cfg.Failure == FailureMalformedJSON {
+		data = data[:len(data)/2]
+	}
+	w.Write(data)
+}
+
+func (h *handler) root(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("Ollama is running"))
+}
+
+func (h *handler) version(w http.ResponseWriter, r *http.Request) {
+	h.writeJSON(w, http.StatusOK, map[string]string{"version": h.cfg.Version})
+}
+
+func (h *handler) tags(w http.ResponseWriter, r *http.Request) {
+	models := make([]map[string]any, 0, len(h.cfg.Models))
+	for _, m := range h.cfg.Models {
+		models = append(models, map[string]any{
+			"name":        m.Name,
+			"model":       m.Name,
+			"size":        m.Size,
+			"modified_at": time.Now().Add(-24 * time.Hour).Format(time.RFC3339),
+			"digest":      fmt.Sprintf("%064x", len(m.Name)),
+			"details":     modelDetails(m),
+		})
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"models": models})
+}
+
+func (h *handler) ps(w http.ResponseWriter, r *http.Request) {
+	models := []map[string]any{}
+	for _, m := range h.cfg.Models {
+		if !m.Running {
+			continue
+		}
+		models = append(models, map[string]any{
+			"name":           m.Name,
+			"model":          m.Name,
+			"size":           m.Size,
+			"size_vram":      m.Size,
+			"expires_at":     time.Now().Add(5 * time.Minute).Format(time.RFC3339),
+			"context_length": 4096,
+			"details":        modelDetails(m),
+		})
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"models": models})
+}
+
+func (h *handler) show(w http.ResponseWriter, r *http.Request) {
+	m, ok := h.findModel(w, r)
+	if !ok {
+		return
+	}
+	family := modelDetails(m)["family"].(string)
+	h.writeJSON(w, http.StatusOK, map[string]any{
+		"license":  m.License,
+		"template": "{{ .Prompt }}",
+		"details":  modelDetails(m),
+		"model_info": map[string]any{
+			"general.architecture":     family,
+			"general.parameter_count":  int64(probe.ParseModelSize(m.Name) * 1e9),
+			family + ".context_length": 131072,
+		},
+		"capabilities": capabilities(m),
+	})
+}
+
+// findModel 读取请求体中的模型名称,模型不存在时直接写入 404 响应
+func (h *handler) findModel(w http.ResponseWriter, r *http.Request) (Model, bool) {
+	var req struct {
+		Model string `json:"model"`
+		Name  string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return Model{}, false
+	}
+	if req.Model == "" {
+		req.Model = req.Name
+	}
+	for _, m := range h.cfg.Models {
+		if m.Name == req.Model {
+			return m, true
+		}
+	}
+	h.writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("model '%s' not found", req.Model)})
+	return Model{}, false
+}
+
+func modelDetails(m Model) map[string]any {
+	family, _, _ := strings.Cut(m.Name, ":")
+	if m.Embedding {
+		family = EmbeddingFamily
+	}
+	return map[string]any{
+		"format":             "gguf",
+		"family":             family,
+		"parameter_size":     fmt.Sprintf("%gB", probe.ParseModelSize(m.Name)),
+		"quantization_level": "Q4_K_M",
+	}
+}
+
+// tokens 为生成接口循环输出的文本片段
+var tokens = []string{"太阳", "发光", "是", "因为", "核心", "发生", "核聚变", "。"}
+
+func (h *handler) generate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model   string `json:"model"`
+		Prompt  string `json:"prompt"`
+		Stream  *bool  `json:"stream"`
+		Options struct {
+			NumPredict int `json:"num_predict"`
+		} `json:"options"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	m, ok := h.model(req.Model)
+	if !ok {
+		h.writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("model '%s' not found", req.Model)})
+		return
+	}
+	if m.Embedding && req.Prompt != "" {
+		h.writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("%q does not support generate", req.Model)})
+		return
+	}
+	// 与 Ollama 一致,不带提示词的请求只加载或卸载模型
+	if req.Prompt == "" {
+		h.writeJSON(w, http.StatusOK, map[string]any{
+			"model":       req.Model,
+			"created_at":  time.Now().Format(time.RFC3339Nano),
+			"response":    "",
+			"done":        true,
+			"done_reason": "load",
+		})
+		return
+	}
+	n := req.Options.NumPredict
+	if n <= 0 {
+		n = defaultNumPredict
+	}
+	if h.cfg.Failure == FailureEndlessStream {
+		n = -1
+	}
+	interval := time.Duration(float64(time.Second) / h.cfg.TokensPerSec)
+	start := time.Now()
+
+	// 未指定 stream 时与 Ollama 一致,默认流式输出
+	if req.Stream != nil && !*req.Stream {
+		if n < 0 {
+			<-r.Context().Done()
+			return
+		}
+		if !sleep(r.Context(), time.Duration(n)*interval) {
+			return
+		}
+		var text strings.Builder
+		for i := range n {
+			text.WriteString(tokens[i%len(tokens)])
+		}
+		h.writeJSON(w, http.StatusOK, h.final(req.Model, text.String(), n, start))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	for i := 0; n < 0 || i < n; i++ {
+		if !sleep(r.Context(), interval) {
+			return
+		}
+		if h.cfg.Failure == FailureMalformedJSON {
+			fmt.Fprintf(w, "{\"model\":%q,\"response\":\n", req.Model)
+		} else {
+			enc.Encode(map[string]any{
+				"model":      req.Model,
+				"created_at": time.Now().Format(time.RFC3339Nano),
+				"response":   tokens[i%len(tokens)],
+				"done":       false,
+			})
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	enc.Encode(h.final(req.Model, "", n, start))
+}
+
+// final 构造生成结束时带统计信息的响应
+func (h *handler) final(model string, text string, count int, start time.Time) map[string]any {
+	total := time.Since(start)
+	return map[string]any{
+		"model":          model,
+		"created_at":     time.Now().Format(time.RFC3339Nano),
+		"response":       text,
+		"done":           true,
+		"done_reason":    "stop",
+		"total_duration": total.Nanoseconds(),
+		"eval_count":     count,
+		"eval_duration":  total.Nanoseconds(),
+	}
+}
+
+func (h *handler) model(name string) (Model, bool) {
+	for _, m := range h.cfg.Models {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
+
+// chat 流式输出回复,提示词处理时间按所有消息的长度估算,与真实服务一样随历史增长
+func (h *handler) chat(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+		Options struct {
+			NumPredict int `json:"num_predict"`
+		} `json:"options"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	m, ok := h.model(req.Model)
+	if !ok {
+		h.writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("model '%s' not found", req.Model)})
+		return
+	}
+	if m.Embedding {
+		h.writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("%q does not support chat", req.Model)})
+		return
+	}
+	n := req.Options.NumPredict
+	if n <= 0 {
+		n = defaultNumPredict
+	}
+	// 每条消息计入模板开销,提示词处理速度为生成速度的 10 倍
+	promptTokens := 0
+	for _, msg := range req.Messages {
+		promptTokens += utf8.RuneCountInString(msg.Content) + 4
+	}
+	interval := time.Duration(float64(time.Second) / h.cfg.TokensPerSec)
+	promptDuration := time.Duration(promptTokens) * interval / 10
+	start := time.Now()
+	if !sleep(r.Context(), promptDuration) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	evalStart := time.Now()
+	for i := range n {
+		if !sleep(r.Context(), interval) {
+			return
+		}
+		enc.Encode(map[string]any{
+			"model":      req.Model,
+			"created_at": time.Now().Format(time.RFC3339Nano),
+			"message":    map[string]string{"role": "assistant", "content": tokens[i%len(tokens)]},
+			"done":       false,
+		})
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	enc.Encode(map[string]any{
+		"model":                req.Model,
+		"created_at":           time.Now().Format(time.RFC3339Nano),
+		"message":              map[string]string{"role": "assistant", "content": ""},
+		"done":                 true,
+		"done_reason":          "stop",
+		"total_duration":       time.Since(start).Nanoseconds(),
+		"prompt_eval_count":    promptTokens,
+		"prompt_eval_duration": promptDuration.Nanoseconds(),
+		"eval_count":           n,
+		"eval_duration":        time.Since(evalStart).Nanoseconds(),
+	})
+}
+
+// capabilities 返回 /api/show 中的模型能力
+func capabilities(m Model) []string {
+	if m.Embedding {
+		return []string{"embedding"}
+	}
+	return []string{"completion"}
+}
+
+// embed 为每个输入返回固定维度的向量,耗时按输入数量和生成速度估算
+func (h *handler) embed(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string          `json:"model"`
+		Input json.RawMessage `json:"input"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	m, ok := h.model(req.Model)
+	if !ok {
+		h.writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("model '%s' not found", req.Model)})
+		return
+	}
+	if !m.Embedding {
+		h.writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("%q does not support embeddings", req.Model)})
+		return
+	}
+	var inputs []string
+	if err := json.Unmarshal(req.Input, &inputs); err != nil {
+		var one string
+		if err := json.Unmarshal(req.Input, &one); err != nil {
+			h.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid input type"})
+			return
+		}
+		inputs = []string{one}
+	}
+	start := time.Now()
+	if !sleep(r.Context(), time.Duration(float64(len(inputs))/h.cfg.TokensPerSec*float64(time.Second))) {
+		return
+	}
+	embeddings := make([][]float64, len(inputs))
+	for i := range embeddings {
+		embeddings[i] = make([]float64, EmbeddingDimension)
+		embeddings[i][i%EmbeddingDimension] = 1
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{
+		"model":             req.Model,
+		"embeddings":        embeddings,
+		"total_duration":    time.Since(start).Nanoseconds(),
+		"prompt_eval_count": len(inputs) * 8,
+	})
+}
+
+// Server 为监听在本地端口上的模拟实例
+type Server struct {
+	srv *http.Server
+	ln  net.Listener
+}
+
+// Start 在 addr 上启动模拟实例,addr 的端口为 0 时随机分配
+func Start(addr string, cfg Config) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("启动模拟服务失败: %w", err)
+	}
+	s := &Server{
+		srv: &http.Server{Handler: Handler(cfg), ReadHeaderTimeout: 10 * time.Second},
+		ln:  ln,
+	}
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Warn("模拟服务异常退出", "addr", ln.Addr().String(), "error", err)
+		}
+	}()
+	return s, nil
+}
+
+// Addr 返回实际监听的地址
+func (s *Server) Addr() *net.TCPAddr {
+	return s.ln.Addr().(*net.TCPAddr)
+}
+
+// URL 返回模拟实例的 API 地址
+func (s *Server) URL() string {
+	return "http://" + s.ln.Addr().String()
+}
+
+// Shutdown 停止接受新连接,并在 ctx 结束后强制关闭未完成的请求
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.srv.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return s.srv.Close()
+	}
+	return err
+}
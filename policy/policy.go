@@ -0,0 +1,152 @@
+// Package policy 负责模型合规策略的加载与检查,根据 /api/show 的元数据找出不符合策略的模型.
+package policy
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aspnmy/ollama_scanner/probe"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy 为准入模型策略,由 POLICY_FILE 指定的 YAML 或 JSON 文件加载
+//
+//	allowed_families: [llama, qwen2]
+//	max_parameter_size: 14B
+//	allowed_quantizations: [Q4_K_M, Q8_0]
+//	banned_licenses: [CC-BY-NC]
+//
+// 未设置的规则不检查.设置了规则但模型缺少对应的元数据时视为违规.
+type Policy struct {
+	// AllowedFamilies 为允许的模型架构,不区分大小写
+	AllowedFamilies []string `yaml:"allowed_families" json:"allowed_families"`
+	// MaxParameterSize 为参数量上限,例如 14B、500M
+	MaxParameterSize string `yaml:"max_parameter_size" json:"max_parameter_size"`
+	// AllowedQuantizations 为允许的量化方式,不区分大小写
+	AllowedQuantizations []string `yaml:"allowed_quantizations" json:"allowed_quantizations"`
+	// BannedLicenses 为禁止的许可证,识别出的许可证包含其中任一项(不区分大小写)即违规
+	BannedLicenses []string `yaml:"banned_licenses" json:"banned_licenses"`
+
+	maxParams float64
+}
+
+// 违规的规则名称
+const (
+	RuleMetadata      = "metadata"
+	RuleFamily        = "family"
+	RuleParameterSize = "parameter_size"
+	RuleQuantization  = "quantization"
+	RuleLicense       = "license"
+)
+
+// Violation 为模型违反的一条规则
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return v.Rule + ": " + v.Message
+}
+
+// Load 读取并校验策略文件
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取策略文件失败: %w", err)
+	}
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("解析策略文件 %s 失败: %w", path, err)
+	}
+	if p.MaxParameterSize != "" {
+		if p.maxParams, err = ParseParameterSize(p.MaxParameterSize); err != nil {
+			return nil, fmt.Errorf("策略文件 %s 的 max_parameter_size 无效: %w", path, err)
+		}
+	}
+	if len(p.AllowedFamilies) == 0 && p.maxParams == 0 && len(p.AllowedQuantizations) == 0 && len(p.BannedLicenses) == 0 {
+		return nil, fmt.Errorf("策略文件 %s 没有任何规则", path)
+	}
+	return &p, nil
+}
+
+// ParseParameterSize 解析参数量,例如 7.6B、494.03M、70b,返回参数个数
+func ParseParameterSize(size string) (float64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	scale := 1.0
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K':
+			scale = 1e3
+		case 'M':
+			scale = 1e6
+		case 'B':
+			scale = 1e9
+		case 'T':
+			scale = 1e12
+		}
+		if scale != 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("参数量格式错误: %q", size)
+	}
+	return n * scale, nil
+}
+
+// Check 返回模型违反的规则,details 为 nil 表示未能获取元数据
+func (p *Policy) Check(details *probe.ModelDetails) []Violation {
+	if details == nil {
+		return []Violation{{Rule: RuleMetadata, Message: "获取模型元数据失败,无法检查策略"}}
+	}
+	var violations []Violation
+	if len(p.AllowedFamilies) > 0 && !containsFold(p.AllowedFamilies, details.Family) {
+		violations = append(violations, Violation{Rule: RuleFamily,
+			Message: fmt.Sprintf("模型架构 %s 不在允许列表中", orUnknown(details.Family))})
+	}
+	if p.maxParams > 0 {
+		params := float64(details.ParameterCount)
+		if params <= 0 {
+			params, _ = ParseParameterSize(details.ParameterSize)
+		}
+		switch {
+		case params <= 0:
+			violations = append(violations, Violation{Rule: RuleParameterSize, Message: "参数量未知"})
+		case params > p.maxParams:
+			violations = append(violations, Violation{Rule: RuleParameterSize,
+				Message: fmt.Sprintf("参数量 %s 超过上限 %s", orUnknown(details.ParameterSize), p.MaxParameterSize)})
+		}
+	}
+	if len(p.AllowedQuantizations) > 0 && !containsFold(p.AllowedQuantizations, details.Quantization) {
+		violations = append(violations, Violation{Rule: RuleQuantization,
+			Message: fmt.Sprintf("量化方式 %s 不在允许列表中", orUnknown(details.Quantization))})
+	}
+	if details.License != "" {
+		license := strings.ToUpper(details.License)
+		for _, banned := range p.BannedLicenses {
+			if strings.Contains(license, strings.ToUpper(banned)) {
+				violations = append(violations, Violation{Rule: RuleLicense,
+					Message: fmt.Sprintf("许可证 %s 被禁止", details.License)})
+				break
+			}
+		}
+	}
+	return violations
+}
+
+// containsFold 判断列表中是否有与 s 相同的项,不区分大小写
+func containsFold(list []string, s string) bool {
+	return s != "" && slices.ContainsFunc(list, func(item string) bool { return strings.EqualFold(item, s) })
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "未知"
+	}
+	return s
+}
@@ -0,0 +1,146 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aspnmy/ollama_scanner/probe"
+)
+
+// writePolicy 把策略内容写入临时文件并返回路径
+func writePolicy(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseParameterSize(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    float64
+		wantErr bool
+	}{
+		{"7B", 7e9, false},
+		{"7.6B", 7.6e9, false},
+		{"70b", 70e9, false},
+		{" 494.03M ", 494.03e6, false},
+		{"1.5K", 1500, false},
+		{"1T", 1e12, false},
+		{"123456", 123456, false},
+		{"", 0, true},
+		{"B", 0, true},
+		{"0B", 0, true},
+		{"-1B", 0, true},
+		{"7X", 0, true},
+		{"seven", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseParameterSize(tt.size)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseParameterSize(%q) = %v, %v", tt.size, got, err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"YAML", "allowed_families: [llama]\nmax_parameter_size: 14B\n", ""},
+		{"JSON", `{"banned_licenses": ["CC-BY-NC"]}`, ""},
+		{"没有规则", "allowed_families: []\n", "没有任何规则"},
+		{"参数量无效", "max_parameter_size: huge\n", "max_parameter_size 无效"},
+		{"格式错误", "allowed_families: [llama\n", "解析策略文件"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Load(writePolicy(t, tt.content))
+			if tt.wantErr == "" {
+				if err != nil || p == nil {
+					t.Fatalf("Load = %v, %v", p, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load err = %v, 期望包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Error("文件不存在时应返回错误")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p, err := Load(writePolicy(t, `
+allowed_families: [llama, Qwen2]
+max_parameter_size: 14B
+allowed_quantizations: [q4_k_m, Q8_0]
+banned_licenses: [cc-by-nc, llama-3.1]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := probe.ModelDetails{Family: "qwen2", ParameterSize: "7.6B", ParameterCount: 7_615_616_512,
+		Quantization: "Q4_K_M", License: "Apache-2.0"}
+	with := func(f func(d *probe.ModelDetails)) *probe.ModelDetails {
+		d := ok
+		f(&d)
+		return &d
+	}
+	tests := []struct {
+		name    string
+		details *probe.ModelDetails
+		want    []string
+	}{
+		{"未获取元数据", nil, []string{RuleMetadata}},
+		{"符合策略", &ok, nil},
+		{"架构不允许", with(func(d *probe.ModelDetails) { d.Family = "gemma" }), []string{RuleFamily}},
+		{"架构未知", with(func(d *probe.ModelDetails) { d.Family = "" }), []string{RuleFamily}},
+		{"参数量超过上限", with(func(d *probe.ModelDetails) { d.ParameterCount = 32e9; d.ParameterSize = "32B" }), []string{RuleParameterSize}},
+		{"参数量等于上限", with(func(d *probe.ModelDetails) { d.ParameterCount = 14e9 }), nil},
+		{"没有参数个数时按参数量文本", with(func(d *probe.ModelDetails) { d.ParameterCount = 0; d.ParameterSize = "70B" }), []string{RuleParameterSize}},
+		{"参数量未知", with(func(d *probe.ModelDetails) { d.ParameterCount = 0; d.ParameterSize = "" }), []string{RuleParameterSize}},
+		{"量化方式不允许", with(func(d *probe.ModelDetails) { d.Quantization = "F16" }), []string{RuleQuantization}},
+		{"许可证被禁止", with(func(d *probe.ModelDetails) { d.License = "CC-BY-NC" }), []string{RuleLicense}},
+		{"许可证按子串匹配", with(func(d *probe.ModelDetails) { d.License = "Llama-3.1" }), []string{RuleLicense}},
+		{"许可证未知时不检查", with(func(d *probe.ModelDetails) { d.License = "" }), nil},
+		{"多项违规", with(func(d *probe.ModelDetails) { d.Family = "gemma"; d.Quantization = "F16" }), []string{RuleFamily, RuleQuantization}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules []string
+			for _, v := range p.Check(tt.details) {
+				rules = append(rules, v.Rule)
+				if v.Message == "" {
+					t.Errorf("违规 %s 缺少说明", v.Rule)
+				}
+			}
+			if !slices.Equal(rules, tt.want) {
+				t.Fatalf("Check = %v, 期望 %v", rules, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckUnsetRules(t *testing.T) {
+	// 只设置许可证规则时其他元数据缺失不算违规
+	p, err := Load(writePolicy(t, "banned_licenses: [CC-BY-NC]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := p.Check(&probe.ModelDetails{}); v != nil {
+		t.Errorf("Check = %v, 期望没有违规", v)
+	}
+	if v := p.Check(nil); len(v) != 1 || v[0].Rule != RuleMetadata || v[0].String() != "metadata: 获取模型元数据失败,无法检查策略" {
+		t.Errorf("Check(nil) = %v", v)
+	}
+}
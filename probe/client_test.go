@@ -0,0 +1,52 @@
+package probe
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLimitedBody(t *testing.T) {
+	const limit = 16
+	tests := []struct {
+		name    string
+		size    int
+		chunked bool // 不发送 Content-Length,只能在读取时发现超限
+		wantErr bool
+	}{
+		{"小于上限", limit - 1, false, false},
+		{"等于上限", limit, false, false},
+		{"等于上限-分块", limit, true, false},
+		{"超过上限-分块", limit + 1, true, true},
+		{"超过上限-Content-Length", limit + 1, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := strings.Repeat("x", tt.size)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.chunked {
+					w.(http.Flusher).Flush()
+				}
+				io.WriteString(w, body)
+			}))
+			defer srv.Close()
+
+			client := NewClient(ClientConfig{MaxResponseSize: limit})
+			resp, err := client.Get(srv.URL)
+			if err == nil {
+				defer resp.Body.Close()
+				var data []byte
+				data, err = io.ReadAll(resp.Body)
+				if err == nil && string(data) != body {
+					t.Fatalf("响应体 = %q, 期望 %q", data, body)
+				}
+			}
+			if got := errors.Is(err, ErrResponseTooLarge); got != tt.wantErr {
+				t.Fatalf("err = %v, 期望超限 %v", err, tt.wantErr)
+			}
+		})
+	}
+}
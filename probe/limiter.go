@@ -0,0 +1,152 @@
+package probe
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultSubnetRateLimit  = 10 // 每个 /24 网段默认每秒请求数
+	DefaultSubnetBurstLimit = 20
+	maxSubnetBuckets        = 4096 // 超过后清理空闲的网段令牌桶,仍然超过时新网段共用一个令牌桶
+)
+
+// tokenBucket 为简单的令牌桶,允许令牌数为负以便排队等待
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// reserve 取走一个令牌并返回需要等待的时间
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// idle 判断令牌桶是否已经回满,回满的令牌桶可以安全丢弃
+func (b *tokenBucket) idle() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens+time.Since(b.last).Seconds()*b.rate >= b.burst
+}
+
+// LimitConfig 为限速配置,速率小于等于 0 表示不限制对应层级
+type LimitConfig struct {
+	Rate        int // 全局每秒请求数
+	Burst       int
+	SubnetRate  int // 每个目标网段每秒请求数
+	SubnetBurst int
+}
+
+// Limiter 同时限制全局请求速率和每个目标网段(IPv4 /24、IPv6 /64)的请求速率
+type Limiter struct {
+	global *tokenBucket
+
+	mu      sync.Mutex
+	subnets map[netip.Prefix]*tokenBucket
+	// overflow 为网段令牌桶达到上限且都在使用时新网段共用的令牌桶
+	overflow    *tokenBucket
+	subnetRate  float64
+	subnetBurst int
+}
+
+// NewLimiter 根据限速配置创建限速器
+func NewLimiter(cfg LimitConfig) *Limiter {
+	l := &Limiter{
+		subnets:     make(map[netip.Prefix]*tokenBucket),
+		subnetRate:  float64(cfg.SubnetRate),
+		subnetBurst: cfg.SubnetBurst,
+	}
+	if cfg.Rate > 0 {
+		burst := cfg.Burst
+		if burst <= 0 {
+			burst = cfg.Rate
+		}
+		l.global = newTokenBucket(float64(cfg.Rate), burst)
+	}
+	return l
+}
+
+// subnetBucket 返回目标所属网段的令牌桶
+func (l *Limiter) subnetBucket(ip string) *tokenBucket {
+	if l.subnetRate <= 0 {
+		return nil
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil
+	}
+	addr = addr.Unmap()
+	bits := 24
+	if addr.Is6() {
+		bits = 64
+	}
+	key, _ := addr.Prefix(bits)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b, ok := l.subnets[key]; ok {
+		return b
+	}
+	if len(l.subnets) >= maxSubnetBuckets {
+		for k, v := range l.subnets {
+			if v.idle() {
+				delete(l.subnets, k)
+			}
+		}
+	}
+	// 清理后仍然达到上限时不再新建令牌桶,新网段共用溢出令牌桶,
+	// 这些网段的总速率不超过单个网段的限速,内存占用保持有界
+	if len(l.subnets) >= maxSubnetBuckets {
+		if l.overflow == nil {
+			l.overflow = newTokenBucket(l.subnetRate, l.subnetBurst)
+		}
+		return l.overflow
+	}
+	b := newTokenBucket(l.subnetRate, l.subnetBurst)
+	l.subnets[key] = b
+	return b
+}
+
+// Wait 阻塞直到全局和目标网段的令牌桶都允许发出请求
+func (l *Limiter) Wait(ctx context.Context, ip string) error {
+	if l == nil {
+		return nil
+	}
+	for _, b := range []*tokenBucket{l.subnetBucket(ip), l.global} {
+		if b == nil {
+			continue
+		}
+		if d := b.reserve(); d > 0 {
+			timer := time.NewTimer(d)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+	return nil
+}
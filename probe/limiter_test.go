@@ -0,0 +1,43 @@
+package probe
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSubnetBuckets(t *testing.T) {
+	l := NewLimiter(LimitConfig{SubnetRate: 1, SubnetBurst: 1})
+	// 同一 /24 和 /64 网段共用令牌桶
+	if l.subnetBucket("10.0.0.1") != l.subnetBucket("10.0.0.200") || l.subnetBucket("10.0.0.1") == l.subnetBucket("10.0.1.1") {
+		t.Error("IPv4 应按 /24 分配令牌桶")
+	}
+	if l.subnetBucket("2001:db8::1") != l.subnetBucket("2001:db8::ffff:1") || l.subnetBucket("::ffff:10.0.0.1") != l.subnetBucket("10.0.0.1") {
+		t.Error("IPv6 应按 /64 分配令牌桶,IPv4 映射地址按 IPv4 处理")
+	}
+	if l.subnetBucket("not-an-ip") != nil || NewLimiter(LimitConfig{}).subnetBucket("10.0.0.1") != nil {
+		t.Error("无效地址或未启用网段限速时不应返回令牌桶")
+	}
+
+	// 所有令牌桶都在使用时,新网段共用溢出令牌桶,数量不超过上限
+	l = NewLimiter(LimitConfig{SubnetRate: 1, SubnetBurst: 1})
+	for i := range maxSubnetBuckets {
+		l.subnetBucket(fmt.Sprintf("10.%d.%d.1", i/256, i%256)).reserve()
+	}
+	a, b := l.subnetBucket("192.0.2.1"), l.subnetBucket("198.51.100.1")
+	if len(l.subnets) != maxSubnetBuckets || a == nil || a != b || a != l.overflow {
+		t.Fatalf("网段令牌桶 %d 个, 溢出令牌桶 %p %p", len(l.subnets), a, b)
+	}
+	// 已有的网段继续使用自己的令牌桶
+	if l.subnetBucket("10.0.0.1") == l.overflow {
+		t.Error("已有网段不应改用溢出令牌桶")
+	}
+
+	// 有空闲的令牌桶时先清理,再为新网段分配令牌桶
+	l = NewLimiter(LimitConfig{SubnetRate: 1, SubnetBurst: 1})
+	for i := range maxSubnetBuckets {
+		l.subnetBucket(fmt.Sprintf("10.%d.%d.1", i/256, i%256))
+	}
+	if b := l.subnetBucket("192.0.2.1"); b == l.overflow || len(l.subnets) != 1 {
+		t.Errorf("清理后网段令牌桶 %d 个", len(l.subnets))
+	}
+}
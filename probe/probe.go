@@ -0,0 +1,204 @@
+// Package probe 实现对单个 Ollama 主机的探测:端口检查、服务识别、
+// 模型列表、已加载模型以及模型元数据.
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotOllama 表示目标端口开放但不是 Ollama 服务
+var ErrNotOllama = errors.New("不是 Ollama 服务")
+
+// Model 对应 /api/tags 返回的本地模型
+type Model struct {
+	Name string
+	Size int64
+	// Family 为 /api/tags 返回的模型架构,例如 llama、nomic-bert
+	Family string
+}
+
+// RunningModel 对应 /api/ps 返回的已加载模型
+type RunningModel struct {
+	Name          string    `json:"name"`
+	Size          int64     `json:"size"`
+	SizeVRAM      int64     `json:"size_vram"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	ContextLength int       `json:"context_length"`
+}
+
+// Prober 对单个主机发起探测请求,所有请求共享同一个 HTTP 客户端和限速器
+type Prober struct {
+	Port    int
+	Timeout time.Duration
+	Client  *http.Client
+	Limiter *Limiter
+}
+
+// WithPort 返回使用另一端口的探测器,与原探测器共享 HTTP 客户端和限速器
+func (p *Prober) WithPort(port int) *Prober {
+	c := *p
+	c.Port = port
+	return &c
+}
+
+// BaseURL 返回目标主机的 Ollama API 地址,IPv6 地址带方括号
+func (p *Prober) BaseURL(ip string) string {
+	return "http://" + net.JoinHostPort(ip, strconv.Itoa(p.Port))
+}
+
+// get 发起带超时和限速的 GET 请求,非 200 响应作为错误返回
+func (p *Prober) get(ctx context.Context, ip string, path string) (*http.Response, context.CancelFunc, error) {
+	if err := p.Limiter.Wait(ctx, ip); err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.BaseURL(ip)+path, nil)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, nil, fmt.Errorf("HTTP错误: %d", resp.StatusCode)
+	}
+	return resp, cancel, nil
+}
+
+// CheckPort 检查目标端口是否可以建立 TCP 连接
+func (p *Prober) CheckPort(ctx context.Context, ip string) error {
+	if err := p.Limiter.Wait(ctx, ip); err != nil {
+		return err
+	}
+	dialer := net.Dialer{Timeout: p.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(p.Port)))
+	if err != nil {
+		return err
+	}
+	conn.Close()
+	return nil
+}
+
+// CheckOllama 检查根路径是否返回 "Ollama is running"
+func (p *Prober) CheckOllama(ctx context.Context, ip string) error {
+	resp, cancel, err := p.get(ctx, ip, "/")
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	buf := make([]byte, 1024)
+	n, err := resp.Body.Read(buf)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if !strings.Contains(string(buf[:n]), "Ollama is running") {
+		return ErrNotOllama
+	}
+	return nil
+}
+
+// Version 通过 /api/version 获取 Ollama 版本号
+func (p *Prober) Version(ctx context.Context, ip string) (string, error) {
+	resp, cancel, err := p.get(ctx, ip, "/api/version")
+	if err != nil {
+		return "", err
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	var data struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return "", fmt.Errorf("解析版本信息失败: %w", err)
+	}
+	return data.Version, nil
+}
+
+// Models 通过 /api/tags 获取本地模型列表
+func (p *Prober) Models(ctx context.Context, ip string) ([]Model, error) {
+	resp, cancel, err := p.get(ctx, ip, "/api/tags")
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	var data struct {
+		Models []struct {
+			Model   string `json:"model"`
+			Size    int64  `json:"size"`
+			Details struct {
+				Family string `json:"family"`
+			} `json:"details"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("解析模型列表失败: %w", err)
+	}
+
+	models := make([]Model, 0, len(data.Models))
+	for _, m := range data.Models {
+		models = append(models, Model{Name: m.Model, Size: m.Size, Family: m.Details.Family})
+	}
+	return models, nil
+}
+
+// Running 通过 /api/ps 获取当前已加载到内存中的模型
+func (p *Prober) Running(ctx context.Context, ip string) ([]RunningModel, error) {
+	resp, cancel, err := p.get(ctx, ip, "/api/ps")
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	var data struct {
+		Models []RunningModel `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("解析已加载模型失败: %w", err)
+	}
+	return data.Models, nil
+}
+
+// ParseModelSize 从模型标签中解析参数规模,例如 deepseek-r1:7b 返回 7
+func ParseModelSize(model string) float64 {
+	parts := strings.Split(model, ":")
+	if len(parts) < 2 {
+		return 0
+	}
+
+	sizeStr := strings.TrimSuffix(parts[len(parts)-1], "b")
+	size, err := strconv.ParseFloat(sizeStr, 64)
+	if err != nil {
+		return 0
+	}
+
+	return size
+}
+
+// SortModels 按参数规模从小到大排序
+func SortModels(models []Model) []Model {
+	sort.Slice(models, func(i, j int) bool {
+		return ParseModelSize(models[i].Name) < ParseModelSize(models[j].Name)
+	})
+	return models
+}
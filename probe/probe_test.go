@@ -0,0 +1,176 @@
+package probe_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/mock"
+	"github.com/aspnmy/ollama_scanner/probe"
+)
+
+// newProber 启动模拟服务并返回指向它的探测器和地址
+func newProber(t *testing.T, cfg mock.Config, client probe.ClientConfig) (*probe.Prober, string) {
+	t.Helper()
+	srv := httptest.NewServer(mock.Handler(cfg))
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+	return &probe.Prober{
+		Port:    port,
+		Timeout: 5 * time.Second,
+		Client:  probe.NewClient(client),
+		Limiter: probe.NewLimiter(probe.LimitConfig{}),
+	}, u.Hostname()
+}
+
+func testModels() []mock.Model {
+	qwen := mock.NewModel("qwen2.5:0.5b")
+	qwen.Running = true
+	return []mock.Model{qwen, mock.NewModel("nomic-embed-text:latest")}
+}
+
+func TestCheckOllamaAndVersion(t *testing.T) {
+	p, ip := newProber(t, mock.Config{Version: "0.9.0"}, probe.ClientConfig{})
+	ctx := context.Background()
+	if err := p.CheckPort(ctx, ip); err != nil {
+		t.Fatalf("CheckPort: %v", err)
+	}
+	if err := p.CheckOllama(ctx, ip); err != nil {
+		t.Fatalf("CheckOllama: %v", err)
+	}
+	v, err := p.Version(ctx, ip)
+	if err != nil || v != "0.9.0" {
+		t.Fatalf("Version = %q, %v", v, err)
+	}
+}
+
+func TestModels(t *testing.T) {
+	p, ip := newProber(t, mock.Config{Models: testModels()}, probe.ClientConfig{})
+	models, err := p.Models(context.Background(), ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []probe.Model{
+		{Name: "qwen2.5:0.5b", Size: mock.NewModel("qwen2.5:0.5b").Size, Family: "qwen2.5"},
+		{Name: "nomic-embed-text:latest", Size: mock.NewModel("nomic-embed-text:latest").Size, Family: mock.EmbeddingFamily},
+	}
+	if !slices.Equal(models, want) {
+		t.Fatalf("Models = %+v, 期望 %+v", models, want)
+	}
+}
+
+func TestRunning(t *testing.T) {
+	p, ip := newProber(t, mock.Config{Models: testModels()}, probe.ClientConfig{})
+	running, err := p.Running(context.Background(), ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(running) != 1 {
+		t.Fatalf("Running = %+v, 期望 1 个模型", running)
+	}
+	m := running[0]
+	if m.Name != "qwen2.5:0.5b" || m.ContextLength != 4096 || m.SizeVRAM != m.Size || m.ExpiresAt.Before(time.Now()) {
+		t.Fatalf("Running[0] = %+v", m)
+	}
+}
+
+func TestShow(t *testing.T) {
+	models := testModels()
+	models[0].License = "Apache License\nVersion 2.0, January 2004"
+	p, ip := newProber(t, mock.Config{Models: models}, probe.ClientConfig{})
+
+	tests := []struct {
+		model string
+		want  probe.ModelDetails
+	}{
+		{"qwen2.5:0.5b", probe.ModelDetails{
+			License: "Apache-2.0", Family: "qwen2.5", ParameterSize: "0.5B", ParameterCount: 500_000_000,
+			Quantization: "Q4_K_M", ContextLength: 131072, Capabilities: []string{"completion"}, HasTemplate: true,
+		}},
+		{"nomic-embed-text:latest", probe.ModelDetails{
+			License: "MIT", Family: mock.EmbeddingFamily, ParameterSize: "0B",
+			Quantization: "Q4_K_M", ContextLength: 131072, Capabilities: []string{"embedding"}, HasTemplate: true,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			got, err := p.Show(context.Background(), ip, tt.model)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(*got) != fmt.Sprint(tt.want) {
+				t.Fatalf("Show = %+v\n期望 %+v", *got, tt.want)
+			}
+		})
+	}
+
+	if _, err := p.Show(context.Background(), ip, "missing:1b"); err == nil {
+		t.Fatal("不存在的模型应返回错误")
+	}
+}
+
+func TestDetectLicense(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"   \n", ""},
+		{"Apache License\nVersion 2.0", "Apache-2.0"},
+		{"MIT License\n\nCopyright (c)", "MIT"},
+		{"LLAMA 3.1 COMMUNITY LICENSE AGREEMENT", "Llama-3.1"},
+		{"llama 3 community license agreement", "Llama-3"},
+		{"Attribution-NonCommercial... Creative Commons Attribution-NonCommercial 4.0", "CC-BY-NC"},
+		{"Custom license\nsecond line", "Custom license"},
+	}
+	for _, tt := range tests {
+		if got := probe.DetectLicense(tt.text); got != tt.want {
+			t.Errorf("DetectLicense(%q) = %q, 期望 %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestOversizedBody(t *testing.T) {
+	var models []mock.Model
+	for i := range 50 {
+		models = append(models, mock.NewModel(fmt.Sprintf("model-%02d:7b", i)))
+	}
+	p, ip := newProber(t, mock.Config{Models: models}, probe.ClientConfig{MaxResponseSize: 1024})
+	if _, err := p.Models(context.Background(), ip); !errors.Is(err, probe.ErrResponseTooLarge) {
+		t.Fatalf("Models err = %v, 期望 ErrResponseTooLarge", err)
+	}
+	// 小响应不受影响
+	if _, err := p.Version(context.Background(), ip); err != nil {
+		t.Fatalf("Version: %v", err)
+	}
+}
+
+func TestFailures(t *testing.T) {
+	ctx := context.Background()
+
+	p, ip := newProber(t, mock.Config{Failure: mock.FailureMalformedJSON}, probe.ClientConfig{})
+	if _, err := p.Models(ctx, ip); err == nil {
+		t.Error("malformed-json: Models 应返回解析错误")
+	}
+
+	p, ip = newProber(t, mock.Config{Token: "secret"}, probe.ClientConfig{})
+	if _, err := p.Models(ctx, ip); err == nil || err.Error() != "HTTP错误: 401" {
+		t.Errorf("未携带令牌: Models err = %v", err)
+	}
+
+	p, ip = newProber(t, mock.Config{Failure: mock.FailureSlowHeaders}, probe.ClientConfig{})
+	p.Timeout = 100 * time.Millisecond
+	if err := p.CheckOllama(ctx, ip); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("slow-headers: CheckOllama err = %v, 期望超时", err)
+	}
+}
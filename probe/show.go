@@ -0,0 +1,121 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ModelDetails 对应 /api/show 返回的模型元数据
+type ModelDetails struct {
+	License        string   `json:"license,omitempty"`
+	Family         string   `json:"family,omitempty"`
+	ParameterSize  string   `json:"parameter_size,omitempty"`
+	ParameterCount int64    `json:"parameter_count,omitempty"`
+	Quantization   string   `json:"quantization,omitempty"`
+	ContextLength  int      `json:"context_length,omitempty"`
+	Capabilities   []string `json:"capabilities,omitempty"`
+	HasSystem      bool     `json:"has_system"`
+	HasTemplate    bool     `json:"has_template"`
+}
+
+// Show 通过 /api/show 获取单个模型的元数据
+func (p *Prober) Show(ctx context.Context, ip string, model string) (*ModelDetails, error) {
+	if err := p.Limiter.Wait(ctx, ip); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	body, _ := json.Marshal(map[string]string{"model": model})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.BaseURL(ip)+"/api/show", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP错误: %d", resp.StatusCode)
+	}
+
+	var data struct {
+		License  string `json:"license"`
+		System   string `json:"system"`
+		Template string `json:"template"`
+		Details  struct {
+			Family            string `json:"family"`
+			ParameterSize     string `json:"parameter_size"`
+			QuantizationLevel string `json:"quantization_level"`
+		} `json:"details"`
+		ModelInfo    map[string]any `json:"model_info"`
+		Capabilities []string       `json:"capabilities"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("解析模型信息失败: %w", err)
+	}
+
+	details := &ModelDetails{
+		License:       DetectLicense(data.License),
+		Family:        data.Details.Family,
+		ParameterSize: data.Details.ParameterSize,
+		Quantization:  data.Details.QuantizationLevel,
+		Capabilities:  data.Capabilities,
+		HasSystem:     data.System != "",
+		HasTemplate:   data.Template != "",
+	}
+	if n, ok := data.ModelInfo["general.parameter_count"].(float64); ok {
+		details.ParameterCount = int64(n)
+	}
+	// 上下文长度的键名以模型架构为前缀,例如 llama.context_length
+	if arch, ok := data.ModelInfo["general.architecture"].(string); ok {
+		if n, ok := data.ModelInfo[arch+".context_length"].(float64); ok {
+			details.ContextLength = int(n)
+		}
+	}
+	return details, nil
+}
+
+// knownLicenses 用于从许可证全文中识别许可证类型,按顺序匹配
+var knownLicenses = []struct {
+	keyword string
+	name    string
+}{
+	{"Apache License", "Apache-2.0"},
+	{"MIT License", "MIT"},
+	{"LLAMA 3.3 COMMUNITY LICENSE", "Llama-3.3"},
+	{"LLAMA 3.2 COMMUNITY LICENSE", "Llama-3.2"},
+	{"LLAMA 3.1 COMMUNITY LICENSE", "Llama-3.1"},
+	{"LLAMA 3 COMMUNITY LICENSE", "Llama-3"},
+	{"LLAMA 2 COMMUNITY LICENSE", "Llama-2"},
+	{"Gemma Terms of Use", "Gemma"},
+	{"Qwen LICENSE AGREEMENT", "Qwen"},
+	{"Creative Commons Attribution-NonCommercial", "CC-BY-NC"},
+	{"DEEPSEEK LICENSE AGREEMENT", "DeepSeek"},
+}
+
+// DetectLicense 从许可证全文中识别许可证类型,无法识别时返回首行内容
+func DetectLicense(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	upper := strings.ToUpper(text)
+	for _, l := range knownLicenses {
+		if strings.Contains(upper, strings.ToUpper(l.keyword)) {
+			return l.name
+		}
+	}
+	firstLine, _, _ := strings.Cut(text, "\n")
+	firstLine = strings.TrimSpace(firstLine)
+	if len([]rune(firstLine)) > 60 {
+		firstLine = string([]rune(firstLine)[:60])
+	}
+	return firstLine
+}
@@ -0,0 +1,203 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// discoveryWaitDelay 为取消后等待扫描器自行退出的时间
+const discoveryWaitDelay = 5 * time.Second
+
+// discover 在给定网段中发现开放端口的主机,返回去重后的 IP 列表.
+// zmap 不支持 IPv6,使用 zmap 时 IPv6 网段改用原生发现;masscan 同时处理 IPv4 和 IPv6.
+func (s *Scanner) discover(ctx context.Context, prefixes []netip.Prefix) ([]string, error) {
+	switch s.cfg.ScannerType {
+	case "native":
+		return s.nativeDiscover(ctx, prefixes)
+	case "masscan":
+		return s.externalDiscover(ctx, prefixes)
+	}
+
+	var v4, v6 []netip.Prefix
+	for _, p := range prefixes {
+		if p.Addr().Is4() {
+			v4 = append(v4, p)
+		} else {
+			v6 = append(v6, p)
+		}
+	}
+	var ips []string
+	if len(v4) > 0 {
+		found, err := s.externalDiscover(ctx, v4)
+		if err != nil {
+			return nil, err
+		}
+		ips = append(ips, found...)
+	}
+	if len(v6) > 0 {
+		s.log.Info("zmap 不支持 IPv6,IPv6 目标使用原生发现", "prefixes", len(v6))
+		found, err := s.nativeDiscover(ctx, v6)
+		if err != nil {
+			return nil, err
+		}
+		ips = append(ips, found...)
+	}
+	return ips, nil
+}
+
+// externalDiscover 调用 zmap 或 masscan 发现开放端口的主机
+func (s *Scanner) externalDiscover(ctx context.Context, prefixes []netip.Prefix) ([]string, error) {
+	masscan := s.cfg.ScannerType == "masscan"
+	inputFile, err := writePrefixes("ollama_scanner_targets_*.txt", prefixes)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(inputFile)
+
+	outputFile, err := os.CreateTemp("", "ollama_scanner_discovery_*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("创建发现结果文件失败: %w", err)
+	}
+	outputFile.Close()
+	defer os.Remove(outputFile.Name())
+
+	// 每次扫描各自写入排除列表,zmap 的排除列表中只能包含 IPv4 网段
+	var deny []netip.Prefix
+	for _, p := range s.cfg.Scope.Excluded() {
+		if masscan || p.Addr().Is4() {
+			deny = append(deny, p)
+		}
+	}
+	var excludeFile string
+	if len(deny) > 0 {
+		excludeFile, err = writePrefixes("ollama_scanner_exclude_*.txt", deny)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(excludeFile)
+	}
+
+	cmd := s.discoveryCommand(ctx, prefixes, inputFile, outputFile.Name(), excludeFile)
+	// 取消时先发送中断信号让扫描器写完输出,超时后再强制结束
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = discoveryWaitDelay
+	s.log.Debug("启动目标发现", "command", strings.Join(cmd.Args, " "))
+
+	out, err := cmd.CombinedOutput()
+	s.log.Info("目标发现结束", "scanner", cmd.Args[0], "output", string(out))
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s 执行失败: %w", cmd.Args[0], err)
+	}
+
+	ips, err := readDiscovered(outputFile.Name())
+	if err != nil {
+		return nil, err
+	}
+	s.log.Info("发现开放端口的主机", "count", len(ips))
+	return ips, nil
+}
+
+// writePrefixes 将网段写入临时文件,供 zmap -w/-b 和 masscan -iL/--excludefile 使用
+func writePrefixes(pattern string, prefixes []netip.Prefix) (string, error) {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("创建目标文件失败: %w", err)
+	}
+	defer file.Close()
+	for _, p := range prefixes {
+		if _, err := fmt.Fprintln(file, p.String()); err != nil {
+			os.Remove(file.Name())
+			return "", fmt.Errorf("写入目标文件失败: %w", err)
+		}
+	}
+	return file.Name(), nil
+}
+
+// discoveryCommand 构建 zmap 或 masscan 命令,启用分片时附加分片参数
+func (s *Scanner) discoveryCommand(ctx context.Context, prefixes []netip.Prefix, inputFile string, outputFile string, excludeFile string) *exec.Cmd {
+	masscan := s.cfg.ScannerType == "masscan"
+	var cmd *exec.Cmd
+	if masscan {
+		cmd = s.masscanCommand(ctx, inputFile, outputFile, excludeFile)
+	} else {
+		cmd = s.zmapCommand(ctx, inputFile, outputFile, excludeFile)
+	}
+	// 各实例使用相同的种子,zmap 和 masscan 才会生成相同的地址排列并互补地分片
+	if shard := s.cfg.Shard; shard.Enabled() {
+		seed := strconv.FormatUint(uint64(shardSeed(prefixes, s.cfg.Port)), 10)
+		if masscan {
+			cmd.Args = append(cmd.Args, "--shard", shard.String(), "--seed", seed)
+		} else {
+			cmd.Args = append(cmd.Args, "--shards", strconv.Itoa(shard.Count),
+				"--shard", strconv.Itoa(shard.Index-1), "--seed", seed)
+		}
+	}
+	return cmd
+}
+
+func (s *Scanner) masscanCommand(ctx context.Context, inputFile string, outputFile string, excludeFile string) *exec.Cmd {
+	args := []string{
+		"-p", strconv.Itoa(s.cfg.Port),
+		"--rate", strconv.Itoa(s.cfg.MasscanRate),
+		"--interface", s.cfg.Interface,
+		"--router-mac", s.cfg.GatewayMAC,
+		"-iL", inputFile,
+		"-oL", outputFile}
+	if excludeFile != "" {
+		args = append(args, "--excludefile", excludeFile)
+	}
+	return exec.CommandContext(ctx, "masscan", args...)
+}
+
+func (s *Scanner) zmapCommand(ctx context.Context, inputFile string, outputFile string, excludeFile string) *exec.Cmd {
+	args := []string{
+		"-p", strconv.Itoa(s.cfg.Port),
+		"-G", strings.Trim(s.cfg.GatewayMAC, "'"), // 移除可能存在的单引号
+		"-w", inputFile,
+		"-o", outputFile,
+		"-T", strconv.Itoa(s.cfg.ZmapThreads)}
+	if excludeFile != "" {
+		args = append(args, "-b", excludeFile)
+	}
+	return exec.CommandContext(ctx, "zmap", args...)
+}
+
+// readDiscovered 读取 zmap(每行一个 IP)或 masscan -oL(open tcp 端口 IP 时间戳)的输出
+func readDiscovered(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开发现结果文件失败: %w", err)
+	}
+	defer file.Close()
+
+	var ips []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ip := line
+		if fields := strings.Fields(line); len(fields) >= 4 && fields[0] == "open" {
+			ip = fields[3]
+		}
+		if !seen[ip] {
+			seen[ip] = true
+			ips = append(ips, ip)
+		}
+	}
+	return ips, scanner.Err()
+}
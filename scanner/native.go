@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sync"
+)
+
+// NativeMaxHosts 为原生发现单次扫描允许展开的最大地址数
+const NativeMaxHosts = 1 << 16
+
+// nativeDiscover 逐个地址建立 TCP 连接发现开放端口的主机,不需要 zmap 或 masscan,
+// 使用与探测相同的限速器.只适用于明确列出的地址和较小的网段,IPv6 /64 等大网段会被拒绝.
+func (s *Scanner) nativeDiscover(ctx context.Context, prefixes []netip.Prefix) ([]string, error) {
+	all, err := expandPrefixes(prefixes)
+	if err != nil {
+		return nil, err
+	}
+	// 先按完整的地址列表分片,再由原生发现自行跳过排除列表
+	var addrs []string
+	for _, addr := range s.cfg.Shard.filter(all) {
+		if s.cfg.Scope.Contains(addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	s.log.Debug("启动原生目标发现", "addresses", len(addrs))
+
+	open := make([]bool, len(addrs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(s.cfg.Workers, len(addrs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				open[i] = s.prober.CheckPort(ctx, addrs[i]) == nil
+			}
+		}()
+	}
+feed:
+	for i := range addrs {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var ips []string
+	for i, ok := range open {
+		if ok {
+			ips = append(ips, addrs[i])
+		}
+	}
+	s.log.Info("发现开放端口的主机", "scanner", "native", "count", len(ips))
+	return ips, nil
+}
+
+// expandPrefixes 按顺序展开网段中的全部地址,总数超过 NativeMaxHosts 时返回错误
+func expandPrefixes(prefixes []netip.Prefix) ([]string, error) {
+	var total uint64
+	for _, p := range prefixes {
+		hostBits := p.Addr().BitLen() - p.Bits()
+		if hostBits > 16 || total+1<<hostBits > NativeMaxHosts {
+			return nil, fmt.Errorf("原生发现最多支持 %d 个地址,%s 超出上限,请列出具体地址或缩小网段", NativeMaxHosts, p)
+		}
+		total += 1 << hostBits
+	}
+
+	all := make([]string, 0, total)
+	for _, p := range prefixes {
+		for addr := p.Addr(); p.Contains(addr); addr = addr.Next() {
+			all = append(all, addr.String())
+			if !addr.Next().IsValid() {
+				break
+			}
+		}
+	}
+	return all, nil
+}
@@ -0,0 +1,123 @@
+package scanner
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/bench"
+	"github.com/aspnmy/ollama_scanner/policy"
+	"github.com/aspnmy/ollama_scanner/probe"
+)
+
+// ScanResult 为单个主机的扫描结果
+type ScanResult struct {
+	IP string `json:"ip"`
+	// Port 为输入文件中为该主机指定的端口,使用扫描端口时为 0
+	Port int `json:"port,omitempty"`
+	// Hostname 为输入文件中解析出该地址的主机名
+	Hostname string `json:"hostname,omitempty"`
+	// Shard 为产生该结果的分片,例如 2/5,未分片时为空
+	Shard string `json:"shard,omitempty"`
+	// Version 为 /api/version 返回的 Ollama 版本,获取失败时为空
+	Version string               `json:"version,omitempty"`
+	Models  []ModelInfo          `json:"models"`
+	Running []probe.RunningModel `json:"running,omitempty"`
+	// DiscoveredAt 为目标发现完成的时间,ProbedAt 为开始探测该主机的时间
+	DiscoveredAt time.Time `json:"discovered_at"`
+	ProbedAt     time.Time `json:"probed_at"`
+}
+
+// ModelInfo 为单个模型的发现和性能测试结果,多次测量时 FirstTokenDelay 和 TokensPerSec 为中位数
+type ModelInfo struct {
+	Name            string              `json:"name"`
+	FirstTokenDelay time.Duration       `json:"first_token_delay_ns"`
+	TokensPerSec    float64             `json:"tokens_per_sec"`
+	Status          string              `json:"status"`
+	Size            int64               `json:"size,omitempty"`
+	SkipReason      string              `json:"skip_reason,omitempty"`
+	Details         *probe.ModelDetails `json:"details,omitempty"`
+	BenchmarkedAt   time.Time           `json:"benchmarked_at,omitzero"`
+	// Bench 和 BenchSamples 为多次测量的统计值和原始样本,只测量一次时为空
+	Bench        *bench.Stats   `json:"bench,omitempty"`
+	BenchSamples []bench.Sample `json:"bench_samples,omitempty"`
+	// Type 为性能测试时识别出的模型类型,嵌入模型为 embedding,生成模型为空
+	Type      string             `json:"type,omitempty"`
+	Embedding *bench.EmbedResult `json:"embedding,omitempty"`
+	// Chat 为对话模式下每轮的测试结果
+	Chat *bench.ChatResult `json:"chat,omitempty"`
+	// Smoke 为冒烟测试结果,未配置冒烟测试或没有适用的用例时为空
+	Smoke *bench.SmokeResult `json:"smoke,omitempty"`
+	// Violations 为违反合规策略的规则,未配置策略或符合策略时为空
+	Violations []policy.Violation `json:"violations,omitempty"`
+}
+
+// StatusText 返回带跳过原因的状态描述
+func (m ModelInfo) StatusText() string {
+	if m.SkipReason != "" {
+		return fmt.Sprintf("%s(%s)", m.Status, m.SkipReason)
+	}
+	return m.Status
+}
+
+// Address 返回主机地址,指定了端口时为 IP:端口
+func (r ScanResult) Address() string {
+	if r.Port == 0 {
+		return r.IP
+	}
+	return net.JoinHostPort(r.IP, strconv.Itoa(r.Port))
+}
+
+// CompareAddress 按地址比较两个结果,IPv4 排在 IPv6 之前,同一地址按端口排序
+func CompareAddress(a ScanResult, b ScanResult) int {
+	x, errX := netip.ParseAddr(a.IP)
+	y, errY := netip.ParseAddr(b.IP)
+	if errX != nil || errY != nil {
+		return cmp.Or(cmp.Compare(a.IP, b.IP), cmp.Compare(a.Port, b.Port))
+	}
+	return cmp.Or(x.Compare(y), cmp.Compare(a.Port, b.Port))
+}
+
+// MergeResults 合并多个分片或多次扫描的结果,同一地址只保留探测时间最新的一条,
+// 探测时间相同时保留模型更多的一条.返回按地址排序的结果和被合并掉的重复条数
+func MergeResults(results []ScanResult) ([]ScanResult, int) {
+	latest := make(map[string]ScanResult, len(results))
+	for _, res := range results {
+		old, ok := latest[res.Address()]
+		if !ok || res.ProbedAt.After(old.ProbedAt) ||
+			res.ProbedAt.Equal(old.ProbedAt) && len(res.Models) > len(old.Models) {
+			latest[res.Address()] = res
+		}
+	}
+	merged := make([]ScanResult, 0, len(latest))
+	for _, res := range latest {
+		merged = append(merged, res)
+	}
+	slices.SortFunc(merged, CompareAddress)
+	return merged, len(results) - len(merged)
+}
+
+// RunningModel 按名称查找已加载的模型
+func (r ScanResult) RunningModel(name string) (probe.RunningModel, bool) {
+	for _, m := range r.Running {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return probe.RunningModel{}, false
+}
+
+// NonCompliant 返回违反合规策略的模型数
+func (r ScanResult) NonCompliant() int {
+	n := 0
+	for _, m := range r.Models {
+		if len(m.Violations) > 0 {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,503 @@
+// Package scanner 将目标发现、主机探测和性能测试组合为一次完整的扫描,
+// 供命令行工具和其他内部工具复用.
+package scanner
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/bench"
+	"github.com/aspnmy/ollama_scanner/logging"
+	"github.com/aspnmy/ollama_scanner/policy"
+	"github.com/aspnmy/ollama_scanner/probe"
+	"github.com/aspnmy/ollama_scanner/scope"
+	"github.com/aspnmy/ollama_scanner/target"
+)
+
+const (
+	DefaultPort        = 11434
+	DefaultTimeout     = 3 * time.Second
+	DefaultWorkers     = 200
+	DefaultInterface   = "eth0"
+	DefaultZmapThreads = 10
+	DefaultMasscanRate = 1000
+	DefaultModelFilter = "deepseek-r1"
+)
+
+// Config 为一次扫描的完整配置,零值字段使用默认值
+type Config struct {
+	Port    int
+	Timeout time.Duration
+	Workers int
+
+	// ScannerType 为 "zmap"、"masscan" 或 "native",native 通过 TCP 连接发现主机,
+	// 使用 zmap 时 IPv6 目标也改用 native
+	ScannerType string
+	Interface   string
+	GatewayMAC  string
+	ZmapThreads int
+	MasscanRate int
+	// InputFile 为目标文件,格式见 target 包.未指定端口的目标先经过端口发现,
+	// 指定了其他端口的单个目标跳过发现直接探测
+	InputFile string
+	// ImportFile 不为空时从 Nmap XML、CSV 或 JSON 清单导入目标并忽略 InputFile,
+	// 导入的主机跳过目标发现直接探测.ImportFormat 和 ImportColumn 见 target.Import
+	ImportFile   string
+	ImportFormat string
+	ImportColumn string
+	// Shard 启用时只扫描本实例负责的部分目标:zmap/masscan 使用自身的分片参数,
+	// 原生发现和直接探测的目标按排序后的序号轮流分配
+	Shard Shard
+
+	// ModelFilter 只保留名称包含该字符串的模型,为空时保留全部模型.设置了 Policy 时
+	// 不匹配的模型仍会记录并检查策略,只是不执行性能测试和冒烟测试
+	ModelFilter  string
+	ModelDetails bool
+	// Policy 非空时对主机上的每个模型检查合规策略,不受 ModelFilter 限制,
+	// 检查需要的 /api/show 元数据同时写入结果
+	Policy *policy.Policy
+
+	DisableBench bool
+	Bench        bench.Config
+
+	RateLimit probe.LimitConfig
+	// Scope 为必填项,扫描前检查输入目标并排除拒绝列表
+	Scope *scope.Scope
+	// HTTP 为所有探测阶段共享的连接配置
+	HTTP probe.ClientConfig
+	// HTTPClient 不为空时直接使用,忽略 HTTP 配置
+	HTTPClient *http.Client
+	// Logger 为空时不输出日志
+	Logger *slog.Logger
+	// Location 为结果中时间戳使用的时区,为空时使用本地时区
+	Location *time.Location
+}
+
+// Scanner 执行一次扫描,不依赖任何全局状态
+type Scanner struct {
+	cfg    Config
+	prober *probe.Prober
+	bench  *bench.Benchmarker
+	log    *slog.Logger
+
+	mu           sync.Mutex
+	err          error
+	discovered   []string
+	discoveredAt time.Time
+	completed    map[string]bool
+	// hostnames 为输入文件中主机名解析出的地址到主机名的映射
+	hostnames map[string]string
+}
+
+// New 根据配置创建扫描器
+func New(cfg Config) *Scanner {
+	if cfg.Port == 0 {
+		cfg.Port = DefaultPort
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultWorkers
+	}
+	if cfg.Interface == "" {
+		cfg.Interface = DefaultInterface
+	}
+	if cfg.ZmapThreads <= 0 {
+		cfg.ZmapThreads = DefaultZmapThreads
+	}
+	if cfg.MasscanRate <= 0 {
+		cfg.MasscanRate = DefaultMasscanRate
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = probe.NewClient(cfg.HTTP)
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logging.Discard()
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+
+	prober := &probe.Prober{
+		Port:    cfg.Port,
+		Timeout: cfg.Timeout,
+		Client:  cfg.HTTPClient,
+		Limiter: probe.NewLimiter(cfg.RateLimit),
+	}
+	return &Scanner{
+		cfg:       cfg,
+		prober:    prober,
+		bench:     bench.New(cfg.Bench, prober),
+		log:       cfg.Logger,
+		completed: make(map[string]bool),
+	}
+}
+
+// Run 检查授权范围后启动扫描,结果通过返回的通道逐个主机发送,
+// 扫描结束后通道关闭,扫描过程中的错误通过 Err 获取.
+// ctx 取消后停止发现和探测,已产生的结果仍会发送完毕后再关闭通道.
+func (s *Scanner) Run(ctx context.Context) (<-chan ScanResult, error) {
+	if s.cfg.Scope == nil {
+		return nil, errors.New("未指定授权范围，拒绝扫描")
+	}
+	// 在发送任何数据包之前解析并检查输入目标
+	targets, err := s.readTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cfg.Scope.Check(target.Prefixes(targets)); err != nil {
+		return nil, err
+	}
+	sweep, direct := s.splitTargets(targets)
+	direct = s.cfg.Shard.filter(direct)
+	if s.cfg.Shard.Enabled() {
+		s.log.Info("启用分片扫描", "shard", s.cfg.Shard.String())
+	}
+
+	results := make(chan ScanResult, 100)
+	go func() {
+		defer close(results)
+
+		var ips []string
+		if len(sweep) > 0 {
+			ips, err = s.discover(ctx, sweep)
+			if err != nil {
+				s.setErr(err)
+				return
+			}
+		}
+		ips = append(ips, direct...)
+		// 指定了扫描端口的目标可能与网段中的地址重复
+		s.sortEndpoints(ips)
+		ips = slices.Compact(ips)
+		s.mu.Lock()
+		s.discovered = ips
+		s.discoveredAt = s.now()
+		s.mu.Unlock()
+
+		s.probeAll(ctx, ips, results)
+		if err := ctx.Err(); err != nil {
+			s.setErr(err)
+		}
+	}()
+	return results, nil
+}
+
+// readTargets 读取导入清单或输入文件中的目标
+func (s *Scanner) readTargets(ctx context.Context) ([]target.Target, error) {
+	if s.cfg.ImportFile != "" {
+		targets, err := target.Import(ctx, s.cfg.ImportFile, s.cfg.ImportFormat, s.cfg.ImportColumn, s.cfg.Port)
+		if err != nil {
+			return nil, fmt.Errorf("导入目标失败: %w", err)
+		}
+		s.log.Info("从清单导入目标,跳过目标发现", "file", s.cfg.ImportFile, "count", len(targets))
+		return targets, nil
+	}
+	targets, err := target.ReadFile(ctx, s.cfg.InputFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取输入文件失败: %w", err)
+	}
+	return targets, nil
+}
+
+// splitTargets 将目标分为需要端口发现的网段和直接探测的地址,并记录主机名.
+// 导入的目标以及指定了其他端口的目标直接探测,使用其他端口时为 地址:端口
+func (s *Scanner) splitTargets(targets []target.Target) ([]netip.Prefix, []string) {
+	var (
+		sweep  []netip.Prefix
+		direct []string
+	)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hostnames = make(map[string]string)
+	seen := make(map[string]bool)
+	for _, t := range targets {
+		addr := t.Prefix.Addr().String()
+		if t.Host != "" && s.hostnames[addr] == "" {
+			s.hostnames[addr] = t.Host
+		}
+		port := cmp.Or(t.Port, s.cfg.Port)
+		if port == s.cfg.Port && s.cfg.ImportFile == "" {
+			sweep = append(sweep, t.Prefix)
+			continue
+		}
+		ep := addr
+		if port != s.cfg.Port {
+			ep = netip.AddrPortFrom(t.Prefix.Addr(), uint16(port)).String()
+		}
+		if !seen[ep] {
+			seen[ep] = true
+			direct = append(direct, ep)
+		}
+	}
+	return sweep, direct
+}
+
+// endpoint 拆分待探测目标,目标为 IP 或 IP:端口,未带端口时使用扫描端口
+func (s *Scanner) endpoint(addr string) (string, int) {
+	if ap, err := netip.ParseAddrPort(addr); err == nil {
+		return ap.Addr().String(), int(ap.Port())
+	}
+	return addr, s.cfg.Port
+}
+
+// sortEndpoints 按地址和端口排序待探测目标,IPv4 在 IPv6 之前
+func (s *Scanner) sortEndpoints(eps []string) {
+	slices.SortFunc(eps, func(a, b string) int {
+		ipA, portA := s.endpoint(a)
+		ipB, portB := s.endpoint(b)
+		return CompareAddress(ScanResult{IP: ipA, Port: portA}, ScanResult{IP: ipB, Port: portB})
+	})
+}
+
+// Err 返回扫描过程中遇到的错误,应在结果通道关闭后调用
+func (s *Scanner) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *Scanner) markCompleted(ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completed[ip] = true
+}
+
+func (s *Scanner) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// probeAll 使用固定数量的 worker 并发探测所有主机
+func (s *Scanner) probeAll(ctx context.Context, ips []string, results chan<- ScanResult) {
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < s.cfg.Workers; i++ {
+		wg.Add(1)
+		go s.worker(ctx, &wg, jobs, results)
+	}
+
+feed:
+	for _, ip := range ips {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- ip:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func (s *Scanner) worker(ctx context.Context, wg *sync.WaitGroup, ips <-chan string, results chan<- ScanResult) {
+	defer wg.Done()
+	for ip := range ips {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			result, ok := s.ProbeHost(ctx, ip)
+			// 被取消的探测不计为完成,以便保存的进度中保留该主机,
+			// 但已经获取到的部分结果仍然输出
+			if ctx.Err() == nil {
+				s.markCompleted(ip)
+			}
+			if ok {
+				s.mu.Lock()
+				result.DiscoveredAt = s.discoveredAt
+				s.mu.Unlock()
+				results <- result
+			}
+		}
+	}
+}
+
+// ProbeHost 对单个目标执行端口检查、Ollama 检查、模型获取和性能测试,
+// 目标为 IP 或使用其他端口时的 IP:端口.未发现模型且没有已加载模型时返回 false
+func (s *Scanner) ProbeHost(ctx context.Context, addr string) (ScanResult, bool) {
+	ip, port := s.endpoint(addr)
+	if s.cfg.Scope == nil || !s.cfg.Scope.Contains(ip) {
+		s.log.Warn("跳过授权范围之外的目标", "ip", addr)
+		return ScanResult{}, false
+	}
+	prober, benchmarker := s.prober, s.bench
+	if port != s.cfg.Port {
+		prober = s.prober.WithPort(port)
+		benchmarker = &bench.Benchmarker{Config: s.bench.Config, Prober: prober}
+	}
+
+	start := time.Now()
+	err := prober.CheckPort(ctx, ip)
+	s.trace(ip, "check_port", start, err)
+	if err != nil {
+		return ScanResult{}, false
+	}
+	start = time.Now()
+	err = prober.CheckOllama(ctx, ip)
+	s.trace(ip, "check_ollama", start, err)
+	if err != nil {
+		return ScanResult{}, false
+	}
+
+	result := ScanResult{IP: ip, Shard: s.cfg.Shard.String(), ProbedAt: s.now()}
+	if port != s.cfg.Port {
+		result.Port = port
+	}
+	s.mu.Lock()
+	result.Hostname = s.hostnames[ip]
+	s.mu.Unlock()
+	start = time.Now()
+	result.Version, err = prober.Version(ctx, ip)
+	s.trace(ip, "version", start, err)
+	start = time.Now()
+	result.Running, err = prober.Running(ctx, ip)
+	s.trace(ip, "running", start, err)
+	for i := range result.Running {
+		result.Running[i].ExpiresAt = result.Running[i].ExpiresAt.In(s.cfg.Location)
+	}
+	start = time.Now()
+	all, err := prober.Models(ctx, ip)
+	s.trace(ip, "models", start, err)
+
+	for _, model := range probe.SortModels(all) {
+		info := ModelInfo{Name: model.Name, Size: model.Size, Status: "发现"}
+		// 冒烟测试与性能测试使用相同的目标限制,性能测试关闭时也可以单独执行
+		smoke := len(benchmarker.Config.Smoke.Tests) > 0
+		if !strings.Contains(model.Name, s.cfg.ModelFilter) {
+			// 设置了合规策略时所有模型都要检查,不匹配过滤条件的模型只记录不测试
+			if s.cfg.Policy == nil {
+				continue
+			}
+			if !s.cfg.DisableBench || smoke {
+				info.SkipReason = "不匹配模型过滤条件"
+			}
+		} else if !s.cfg.DisableBench || smoke {
+			if reason := benchmarker.SkipReason(ip, model.Size); reason != "" {
+				info.SkipReason = reason
+				s.log.Debug("跳过性能测试", "ip", ip, "model", model.Name, "reason", reason)
+			} else {
+				// 通过 /api/show 的能力列表识别嵌入模型,未启用模型元数据时结果不写入 Details
+				start = time.Now()
+				details, err := prober.Show(ctx, ip, model.Name)
+				s.trace(ip, "show", start, err)
+				if s.wantDetails() {
+					info.Details = details
+				}
+				if isEmbedding(model, details) {
+					info.Type = bench.ModelTypeEmbedding
+				}
+				if !s.cfg.DisableBench {
+					s.benchModel(ctx, benchmarker, ip, model.Name, &info)
+				}
+				// 嵌入模型不生成文本,不执行冒烟测试
+				if smoke && info.Type != bench.ModelTypeEmbedding {
+					start = time.Now()
+					info.Smoke = benchmarker.RunSmoke(ctx, ip, model.Name)
+					s.log.Debug("探测阶段完成", "ip", ip, "stage", "smoke", "model", model.Name,
+						"duration", time.Since(start))
+				}
+			}
+		}
+		result.Models = append(result.Models, info)
+	}
+	// 已加载但未出现在模型列表中的模型也需要记录
+	for _, m := range result.Running {
+		if !slices.ContainsFunc(result.Models, func(t ModelInfo) bool { return t.Name == m.Name }) {
+			result.Models = append(result.Models, ModelInfo{Name: m.Name, Status: "已加载"})
+		}
+	}
+
+	if len(result.Models) == 0 {
+		return ScanResult{}, false
+	}
+	if s.wantDetails() {
+		for i := range result.Models {
+			if result.Models[i].Details != nil {
+				continue
+			}
+			start = time.Now()
+			result.Models[i].Details, err = prober.Show(ctx, ip, result.Models[i].Name)
+			s.trace(ip, "show", start, err)
+		}
+	}
+	if s.cfg.Policy != nil {
+		for i := range result.Models {
+			m := &result.Models[i]
+			m.Violations = s.cfg.Policy.Check(m.Details)
+			for _, v := range m.Violations {
+				s.log.Warn("模型违反策略", "ip", ip, "model", m.Name, "rule", v.Rule, "message", v.Message)
+			}
+		}
+	}
+	s.log.Info("发现 Ollama 主机", "ip", ip, "models", len(result.Models), "running", len(result.Running))
+	return result, true
+}
+
+// isEmbedding 判断模型是否为嵌入模型,优先使用 /api/show 的能力列表,
+// 获取失败时按 /api/tags 中的模型架构判断
+func isEmbedding(model probe.Model, details *probe.ModelDetails) bool {
+	if details == nil {
+		return bench.IsEmbedding(model.Family, nil)
+	}
+	family := details.Family
+	if family == "" {
+		family = model.Family
+	}
+	return bench.IsEmbedding(family, details.Capabilities)
+}
+
+// benchModel 按模型类型和测试模式执行性能测试,结果写入 info
+func (s *Scanner) benchModel(ctx context.Context, benchmarker *bench.Benchmarker, ip string, model string, info *ModelInfo) {
+	start := time.Now()
+	info.BenchmarkedAt = s.now()
+	var r bench.Result
+	switch {
+	case info.Type == bench.ModelTypeEmbedding:
+		r = benchmarker.RunEmbed(ctx, ip, model)
+	case benchmarker.Config.Mode == bench.ModeChat:
+		r = benchmarker.RunChat(ctx, ip, model)
+	default:
+		r = benchmarker.Run(ctx, ip, model)
+	}
+	s.log.Debug("探测阶段完成", "ip", ip, "stage", "bench", "model", model,
+		"duration", time.Since(start), "status", r.Status)
+	info.FirstTokenDelay = r.FirstTokenDelay
+	info.TokensPerSec = r.TokensPerSec
+	info.Status = r.Status
+	info.Bench, info.BenchSamples, info.Embedding, info.Chat = r.Stats, r.Samples, r.Embedding, r.Chat
+	for i := range info.BenchSamples {
+		info.BenchSamples[i].StartedAt = info.BenchSamples[i].StartedAt.In(s.cfg.Location)
+	}
+}
+
+// wantDetails 判断是否需要通过 /api/show 获取模型元数据
+func (s *Scanner) wantDetails() bool {
+	return s.cfg.ModelDetails || s.cfg.Policy != nil
+}
+
+// now 返回配置时区下的当前时间
+func (s *Scanner) now() time.Time {
+	return time.Now().In(s.cfg.Location)
+}
+
+// trace 记录单个探测阶段的耗时和失败原因
+func (s *Scanner) trace(ip string, stage string, start time.Time, err error) {
+	if err != nil {
+		s.log.Debug("探测阶段失败", "ip", ip, "stage", stage, "duration", time.Since(start), "error", err)
+		return
+	}
+	s.log.Debug("探测阶段完成", "ip", ip, "stage", stage, "duration", time.Since(start))
+}
@@ -0,0 +1,212 @@
+package scanner_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/bench"
+	"github.com/aspnmy/ollama_scanner/mock"
+	"github.com/aspnmy/ollama_scanner/policy"
+	"github.com/aspnmy/ollama_scanner/scanner"
+	"github.com/aspnmy/ollama_scanner/scope"
+)
+
+// testScope 返回只授权本机回环网段的授权范围
+func testScope(t *testing.T) *scope.Scope {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scope.json")
+	data := `{"owner":"测试","authorization":"TEST-1","allow":["127.0.0.0/8"]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sc, err := scope.Load(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sc
+}
+
+// testPolicy 根据策略内容加载合规策略
+func testPolicy(t *testing.T, content string) *policy.Policy {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := policy.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// startMock 启动模拟服务,返回其端口和地址
+func startMock(t *testing.T, cfg mock.Config) (int, string) {
+	t.Helper()
+	if cfg.TokensPerSec == 0 {
+		cfg.TokensPerSec = 2000
+	}
+	srv := httptest.NewServer(mock.Handler(cfg))
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+	return port, u.Hostname()
+}
+
+func testModels() []mock.Model {
+	qwen := mock.NewModel("qwen2.5:0.5b")
+	qwen.Running = true
+	return []mock.Model{mock.NewModel("deepseek-r1:7b"), qwen, mock.NewModel("nomic-embed-text:latest")}
+}
+
+func TestProbeHost(t *testing.T) {
+	port, ip := startMock(t, mock.Config{Models: testModels()})
+	s := scanner.New(scanner.Config{
+		Port:     port,
+		Scope:    testScope(t),
+		Location: time.UTC,
+		Bench: bench.Config{
+			NumPredict: 8,
+			Owned:      []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")},
+		},
+	})
+	res, ok := s.ProbeHost(context.Background(), ip)
+	if !ok {
+		t.Fatal("ProbeHost 未发现主机")
+	}
+	if res.IP != ip || res.Port != 0 || res.Version != mock.DefaultVersion || res.ProbedAt.IsZero() {
+		t.Fatalf("ScanResult = %+v", res)
+	}
+	if len(res.Running) != 1 || res.Running[0].Name != "qwen2.5:0.5b" || res.Running[0].ExpiresAt.Location() != time.UTC {
+		t.Fatalf("Running = %+v", res.Running)
+	}
+
+	// 模型按参数规模从小到大排列
+	var names []string
+	for _, m := range res.Models {
+		names = append(names, m.Name)
+	}
+	want := []string{"nomic-embed-text:latest", "qwen2.5:0.5b", "deepseek-r1:7b"}
+	if len(names) != len(want) {
+		t.Fatalf("Models = %v, 期望 %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("Models = %v, 期望 %v", names, want)
+		}
+	}
+
+	embed, qwen := res.Models[0], res.Models[1]
+	if embed.Type != bench.ModelTypeEmbedding || embed.Embedding == nil || embed.Status != "完成" {
+		t.Errorf("嵌入模型 = %+v", embed)
+	}
+	if qwen.Type != "" || qwen.Status != "完成" || qwen.TokensPerSec <= 0 || qwen.BenchmarkedAt.IsZero() {
+		t.Errorf("生成模型 = %+v", qwen)
+	}
+	// 未启用 ModelDetails 时不输出元数据
+	if qwen.Details != nil {
+		t.Errorf("Details = %+v, 期望为空", qwen.Details)
+	}
+	if _, ok := res.RunningModel("qwen2.5:0.5b"); !ok {
+		t.Error("RunningModel 未找到已加载模型")
+	}
+}
+
+func TestProbeHostOptions(t *testing.T) {
+	port, ip := startMock(t, mock.Config{Models: testModels()})
+	tests := []struct {
+		name  string
+		cfg   scanner.Config
+		check func(t *testing.T, res scanner.ScanResult)
+	}{
+		{
+			name: "非自有端点跳过性能测试",
+			cfg:  scanner.Config{ModelFilter: "qwen"},
+			check: func(t *testing.T, res scanner.ScanResult) {
+				if len(res.Models) != 1 {
+					t.Fatalf("Models = %+v", res.Models)
+				}
+				m := res.Models[0]
+				if m.SkipReason == "" || m.Status != "发现" || !m.BenchmarkedAt.IsZero() {
+					t.Errorf("Model = %+v", m)
+				}
+			},
+		},
+		{
+			name: "关闭性能测试并获取元数据",
+			cfg: scanner.Config{DisableBench: true, ModelDetails: true, ModelFilter: "deepseek",
+				Bench: bench.Config{Owned: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}}},
+			check: func(t *testing.T, res scanner.ScanResult) {
+				// 已加载但不匹配过滤条件的模型同样记录
+				if len(res.Models) != 2 || res.Models[1].Name != "qwen2.5:0.5b" || res.Models[1].Status != "已加载" {
+					t.Fatalf("Models = %+v", res.Models)
+				}
+				m := res.Models[0]
+				if m.Status != "发现" || m.SkipReason != "" || m.Details == nil || m.Details.Family != "deepseek-r1" {
+					t.Errorf("Model = %+v", m)
+				}
+			},
+		},
+		{
+			name: "合规策略检查全部模型",
+			cfg:  scanner.Config{DisableBench: true, ModelFilter: "qwen", Policy: testPolicy(t, "allowed_families: [qwen2.5]\n")},
+			check: func(t *testing.T, res scanner.ScanResult) {
+				// 不匹配过滤条件的模型同样检查策略
+				var violated []string
+				for _, m := range res.Models {
+					if len(m.Violations) > 0 {
+						violated = append(violated, m.Name)
+					}
+				}
+				if len(res.Models) != 3 || strings.Join(violated, " ") != "nomic-embed-text:latest deepseek-r1:7b" {
+					t.Fatalf("Models = %+v", res.Models)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Port, tt.cfg.Scope, tt.cfg.Location = port, testScope(t), time.UTC
+			res, ok := scanner.New(tt.cfg).ProbeHost(context.Background(), ip)
+			if !ok {
+				t.Fatal("ProbeHost 未发现主机")
+			}
+			tt.check(t, res)
+		})
+	}
+}
+
+func TestProbeHostRejects(t *testing.T) {
+	port, ip := startMock(t, mock.Config{})
+	sc := testScope(t)
+
+	// 授权范围之外的目标不发起任何请求
+	s := scanner.New(scanner.Config{Port: port, Scope: sc})
+	if _, ok := s.ProbeHost(context.Background(), "192.0.2.1"); ok {
+		t.Error("授权范围之外的目标不应被探测")
+	}
+	// 不是 Ollama 的端口
+	notOllama := httptest.NewServer(nil)
+	defer notOllama.Close()
+	u, _ := url.Parse(notOllama.URL)
+	if _, ok := s.ProbeHost(context.Background(), u.Host); ok {
+		t.Error("非 Ollama 服务不应返回结果")
+	}
+	// 指定其他端口时结果记录端口
+	other := scanner.New(scanner.Config{Port: 1, Scope: sc})
+	res, ok := other.ProbeHost(context.Background(), ip+":"+strconv.Itoa(port))
+	if !ok || res.Port != port {
+		t.Errorf("ProbeHost(ip:port) = %+v, %v", res, ok)
+	}
+}
@@ -0,0 +1,76 @@
+package scanner
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Shard 表示把目标空间平均分给 Count 个实例时的第 Index 个分片(从 1 开始),
+// 零值表示不分片.所有实例必须使用相同的输入,才能保证分片互不重叠且合起来覆盖全部目标.
+type Shard struct {
+	Index int
+	Count int
+}
+
+// ParseShard 解析 "2/5" 格式的分片,空字符串表示不分片
+func ParseShard(s string) (Shard, error) {
+	if s == "" {
+		return Shard{}, nil
+	}
+	i, n, ok := strings.Cut(s, "/")
+	index, errI := strconv.Atoi(strings.TrimSpace(i))
+	count, errN := strconv.Atoi(strings.TrimSpace(n))
+	if !ok || errI != nil || errN != nil {
+		return Shard{}, fmt.Errorf("分片格式应为 序号/总数,例如 2/5: %q", s)
+	}
+	if count < 1 || index < 1 || index > count {
+		return Shard{}, fmt.Errorf("分片序号必须在 1 到总数之间: %q", s)
+	}
+	return Shard{Index: index, Count: count}, nil
+}
+
+// Enabled 判断是否启用了分片
+func (s Shard) Enabled() bool {
+	return s.Count > 1
+}
+
+func (s Shard) String() string {
+	if !s.Enabled() {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// owns 判断排序后第 i 个目标是否属于本分片
+func (s Shard) owns(i int) bool {
+	return !s.Enabled() || i%s.Count == s.Index-1
+}
+
+// filter 按序号轮流分配目标,返回属于本分片的部分
+func (s Shard) filter(items []string) []string {
+	if !s.Enabled() {
+		return items
+	}
+	var out []string
+	for i, item := range items {
+		if s.owns(i) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// shardSeed 根据目标网段和端口生成 zmap/masscan 的随机种子,
+// 各实例输入相同时种子相同,分片才能互补
+func shardSeed(prefixes []netip.Prefix, port int) uint32 {
+	h := fnv.New32a()
+	for _, p := range prefixes {
+		h.Write([]byte(p.String()))
+		h.Write([]byte{'\n'})
+	}
+	h.Write([]byte(strconv.Itoa(port)))
+	return h.Sum32()
+}
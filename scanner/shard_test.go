@@ -0,0 +1,204 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseShard(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Shard
+		wantErr bool
+	}{
+		{"", Shard{}, false},
+		{"1/1", Shard{Index: 1, Count: 1}, false},
+		{"2/5", Shard{Index: 2, Count: 5}, false},
+		{" 3 / 4 ", Shard{Index: 3, Count: 4}, false},
+		{"0/5", Shard{}, true},
+		{"6/5", Shard{}, true},
+		{"1/0", Shard{}, true},
+		{"2", Shard{}, true},
+		{"a/b", Shard{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseShard(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseShard(%q) = %+v, %v", tt.in, got, err)
+		}
+	}
+	if (Shard{Index: 1, Count: 1}).Enabled() || (Shard{Index: 1, Count: 1}).String() != "" {
+		t.Error("总数为 1 的分片不应启用")
+	}
+}
+
+func TestDiscoveryCommand(t *testing.T) {
+	prefixes := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.0.0/16")}
+	seed := strconv.FormatUint(uint64(shardSeed(prefixes, 11434)), 10)
+	base := Config{Port: 11434, GatewayMAC: "aa:bb:cc:dd:ee:ff", Interface: "eth1", ZmapThreads: 4, MasscanRate: 500}
+	tests := []struct {
+		name        string
+		scanner     string
+		shard       Shard
+		excludeFile string
+		want        []string
+	}{
+		{"zmap", "zmap", Shard{}, "", []string{"zmap",
+			"-p", "11434", "-G", "aa:bb:cc:dd:ee:ff", "-w", "in.txt", "-o", "out.txt", "-T", "4"}},
+		{"zmap 分片", "zmap", Shard{Index: 2, Count: 3}, "deny.txt", []string{"zmap",
+			"-p", "11434", "-G", "aa:bb:cc:dd:ee:ff", "-w", "in.txt", "-o", "out.txt", "-T", "4", "-b", "deny.txt",
+			"--shards", "3", "--shard", "1", "--seed", seed}},
+		{"masscan", "masscan", Shard{Index: 1, Count: 1}, "", []string{"masscan",
+			"-p", "11434", "--rate", "500", "--interface", "eth1", "--router-mac", "aa:bb:cc:dd:ee:ff",
+			"-iL", "in.txt", "-oL", "out.txt"}},
+		{"masscan 分片", "masscan", Shard{Index: 3, Count: 3}, "deny.txt", []string{"masscan",
+			"-p", "11434", "--rate", "500", "--interface", "eth1", "--router-mac", "aa:bb:cc:dd:ee:ff",
+			"-iL", "in.txt", "-oL", "out.txt", "--excludefile", "deny.txt",
+			"--shard", "3/3", "--seed", seed}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			cfg.ScannerType, cfg.Shard = tt.scanner, tt.shard
+			cmd := New(cfg).discoveryCommand(context.Background(), prefixes, "in.txt", "out.txt", tt.excludeFile)
+			if !slices.Equal(cmd.Args, tt.want) {
+				t.Fatalf("Args = %q\n期望 %q", cmd.Args, tt.want)
+			}
+		})
+	}
+}
+
+func TestShardSeed(t *testing.T) {
+	a := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	b := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/9")}
+	if shardSeed(a, 11434) != shardSeed(slices.Clone(a), 11434) {
+		t.Error("相同输入的种子应相同")
+	}
+	if shardSeed(a, 11434) == shardSeed(b, 11434) || shardSeed(a, 11434) == shardSeed(a, 8080) {
+		t.Error("不同输入或端口的种子应不同")
+	}
+}
+
+func TestExpandPrefixes(t *testing.T) {
+	tests := []struct {
+		prefixes []string
+		want     int
+		wantErr  bool
+	}{
+		{[]string{"10.0.0.1/32"}, 1, false},
+		{[]string{"10.0.0.0/30", "10.0.1.0/31"}, 6, false},
+		{[]string{"10.0.0.0/16"}, NativeMaxHosts, false},
+		{[]string{"2001:db8::/112"}, NativeMaxHosts, false},
+		{[]string{"10.0.0.0/15"}, 0, true},
+		{[]string{"10.0.0.0/16", "10.1.0.0/32"}, 0, true},
+		{[]string{"10.0.0.0/17", "10.1.0.0/17", "10.2.0.0/32"}, 0, true},
+		{[]string{"2001:db8::/64"}, 0, true},
+	}
+	for _, tt := range tests {
+		var prefixes []netip.Prefix
+		for _, p := range tt.prefixes {
+			prefixes = append(prefixes, netip.MustParsePrefix(p))
+		}
+		got, err := expandPrefixes(prefixes)
+		if (err != nil) != tt.wantErr || len(got) != tt.want {
+			t.Errorf("expandPrefixes(%v) = %d 个地址, %v", tt.prefixes, len(got), err)
+		}
+	}
+
+	// 地址按网段顺序展开,不越过地址空间末尾
+	got, _ := expandPrefixes([]netip.Prefix{netip.MustParsePrefix("10.0.0.4/31"), netip.MustParsePrefix("255.255.255.254/31")})
+	if want := []string{"10.0.0.4", "10.0.0.5", "255.255.255.254", "255.255.255.255"}; !slices.Equal(got, want) {
+		t.Errorf("expandPrefixes = %v, 期望 %v", got, want)
+	}
+}
+
+func TestShardPartition(t *testing.T) {
+	all, err := expandPrefixes([]netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/22"),
+		netip.MustParsePrefix("192.168.1.7/32"),
+		netip.MustParsePrefix("2001:db8::/120"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, count := range []int{1, 2, 3, 7, 64} {
+		t.Run(fmt.Sprintf("%d 个分片", count), func(t *testing.T) {
+			owner := map[string]int{}
+			sizes := make([]int, count)
+			for index := 1; index <= count; index++ {
+				for _, addr := range (Shard{Index: index, Count: count}).filter(all) {
+					if prev, ok := owner[addr]; ok {
+						t.Fatalf("%s 同时属于分片 %d 和 %d", addr, prev, index)
+					}
+					owner[addr] = index
+					sizes[index-1]++
+				}
+			}
+			if len(owner) != len(all) {
+				t.Fatalf("分片共覆盖 %d 个地址, 期望 %d", len(owner), len(all))
+			}
+			// 轮流分配时各分片大小最多相差 1
+			if slices.Max(sizes)-slices.Min(sizes) > 1 {
+				t.Errorf("分片大小不均匀: %v", sizes)
+			}
+		})
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	models := func(n int) []ModelInfo { return make([]ModelInfo, n) }
+	results := []ScanResult{
+		{IP: "10.0.0.2", ProbedAt: t0, Shard: "1/2"},
+		{IP: "10.0.0.10", ProbedAt: t0, Models: models(1)},
+		{IP: "10.0.0.2", ProbedAt: t0.Add(time.Minute), Shard: "2/2"},
+		{IP: "10.0.0.10", ProbedAt: t0, Models: models(3)},
+		{IP: "10.0.0.10", ProbedAt: t0, Models: models(2)},
+		{IP: "2001:db8::1", ProbedAt: t0},
+		{IP: "10.0.0.2", Port: 8080, ProbedAt: t0},
+		{IP: "10.0.0.2", ProbedAt: t0.Add(-time.Minute), Models: models(5)},
+	}
+	merged, dup := MergeResults(results)
+	if dup != 4 {
+		t.Errorf("重复条数 = %d, 期望 4", dup)
+	}
+	var got []string
+	for _, res := range merged {
+		got = append(got, fmt.Sprintf("%s/%s/%d", res.Address(), res.Shard, len(res.Models)))
+	}
+	want := []string{"10.0.0.2/2/2/0", "10.0.0.2:8080//0", "10.0.0.10//3", "2001:db8::1//0"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("MergeResults = %v, 期望 %v", got, want)
+	}
+}
+
+func TestReadDiscovered(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"zmap", "10.0.0.2\n10.0.0.1\n\n10.0.0.2\n", []string{"10.0.0.2", "10.0.0.1"}},
+		{"masscan", "#masscan\nopen tcp 11434 10.0.0.3 1700000000\nopen tcp 11434 2001:db8::1 1700000001\n" +
+			"open tcp 11434 10.0.0.3 1700000002\n# end\n", []string{"10.0.0.3", "2001:db8::1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readDiscovered(path)
+			if err != nil || !slices.Equal(got, tt.want) {
+				t.Fatalf("readDiscovered = %v, %v, 期望 %v", got, err, tt.want)
+			}
+		})
+	}
+}
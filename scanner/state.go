@@ -0,0 +1,54 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// State 记录扫描进度,扫描被中断时由调用方保存
+type State struct {
+	InputFile   string    `json:"input_file"`
+	Shard       string    `json:"shard,omitempty"`
+	Discovered  []string  `json:"discovered"`
+	Completed   []string  `json:"completed"`
+	Pending     []string  `json:"pending"`
+	Interrupted bool      `json:"interrupted"`
+	SavedAt     time.Time `json:"saved_at"`
+}
+
+// State 返回当前扫描进度的快照
+func (s *Scanner) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := State{
+		InputFile:  s.cfg.InputFile,
+		Shard:      s.cfg.Shard.String(),
+		Discovered: append([]string(nil), s.discovered...),
+		Completed:  make([]string, 0, len(s.completed)),
+		SavedAt:    s.now(),
+	}
+	for _, ip := range s.discovered {
+		if s.completed[ip] {
+			st.Completed = append(st.Completed, ip)
+		} else {
+			st.Pending = append(st.Pending, ip)
+		}
+	}
+	st.Interrupted = len(st.Pending) > 0
+	return st
+}
+
+// Save 将扫描进度写入 JSON 文件
+func (st State) Save(path string) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("保存扫描进度失败: %w", err)
+	}
+	return nil
+}
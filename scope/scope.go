@@ -0,0 +1,189 @@
+// Package scope 负责扫描授权范围的加载与检查,确保只扫描被授权的网段.
+package scope
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Scope 描述一次扫描被授权的范围,由 SCOPE_FILE 指定的 JSON 文件加载
+//
+//	{
+//	  "owner": "网络运维组",
+//	  "authorization": "CHG-2025-0042",
+//	  "allow": ["10.0.0.0/8"],
+//	  "deny": ["10.0.5.0/24"]
+//	}
+//
+// deny 中的网段以及 DENYLIST_FILE 中的网段始终被排除.
+type Scope struct {
+	Owner         string   `json:"owner"`
+	Authorization string   `json:"authorization"`
+	Allow         []string `json:"allow"`
+	Deny          []string `json:"deny"`
+
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// Load 加载授权范围文件,并合并始终生效的拒绝列表文件
+func Load(scopeFile string, denylistFile string) (*Scope, error) {
+	if scopeFile == "" {
+		return nil, fmt.Errorf("必须通过 SCOPE_FILE 指定授权范围文件")
+	}
+
+	data, err := os.ReadFile(scopeFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取授权范围文件失败: %w", err)
+	}
+	var s Scope
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("解析授权范围文件 %s 失败: %w", scopeFile, err)
+	}
+	if s.Owner == "" {
+		return nil, fmt.Errorf("授权范围文件 %s 缺少 owner", scopeFile)
+	}
+	if s.Authorization == "" {
+		return nil, fmt.Errorf("授权范围文件 %s 缺少 authorization", scopeFile)
+	}
+	if len(s.Allow) == 0 {
+		return nil, fmt.Errorf("授权范围文件 %s 未列出任何授权网段", scopeFile)
+	}
+
+	for _, c := range s.Allow {
+		p, err := ParseTarget(c)
+		if err != nil {
+			return nil, fmt.Errorf("授权范围文件 %s 中的 allow 条目无效: %w", scopeFile, err)
+		}
+		s.allow = append(s.allow, p)
+	}
+	for _, c := range s.Deny {
+		p, err := ParseTarget(c)
+		if err != nil {
+			return nil, fmt.Errorf("授权范围文件 %s 中的 deny 条目无效: %w", scopeFile, err)
+		}
+		s.deny = append(s.deny, p)
+	}
+
+	if denylistFile != "" {
+		prefixes, err := ReadTargetFile(denylistFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取拒绝列表失败: %w", err)
+		}
+		s.deny = append(s.deny, prefixes...)
+	}
+
+	return &s, nil
+}
+
+// ParseTarget 将 CIDR 或单个 IP 解析为网段
+func ParseTarget(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("无法解析网段 %q: %w", s, err)
+		}
+		return p.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("无法解析地址 %q: %w", s, err)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ReadTargetFile 读取每行一个 CIDR 或 IP 的文件,忽略空行和 # 注释
+func ReadTargetFile(path string) ([]netip.Prefix, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var (
+		prefixes []netip.Prefix
+		problems []string
+		lineNo   int
+	)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := ParseTarget(line)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s:%d: %v", path, lineNo, err))
+			continue
+		}
+		prefixes = append(prefixes, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	return prefixes, nil
+}
+
+// covers 判断网段 p 是否完整落在 outer 之内
+func covers(outer netip.Prefix, p netip.Prefix) bool {
+	return outer.Addr().Is4() == p.Addr().Is4() &&
+		outer.Bits() <= p.Bits() && outer.Contains(p.Addr())
+}
+
+// AddDeny 追加始终排除的网段
+func (s *Scope) AddDeny(prefixes ...netip.Prefix) {
+	s.deny = append(s.deny, prefixes...)
+}
+
+// Contains 判断 IP 是否在授权范围内且不在拒绝列表中
+func (s *Scope) Contains(ip string) bool {
+	return !Match(s.deny, ip) && Match(s.allow, ip)
+}
+
+// Match 判断 IP 是否落在任一网段内
+func Match(prefixes []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrOutOfScope 表示部分目标不在授权范围内
+var ErrOutOfScope = errors.New("以下目标不在授权范围内")
+
+// Check 检查所有网段都完整落在授权范围内
+func (s *Scope) Check(targets []netip.Prefix) error {
+	var outside []string
+	for _, t := range targets {
+		if !slices.ContainsFunc(s.allow, func(a netip.Prefix) bool { return covers(a, t) }) {
+			outside = append(outside, t.String())
+		}
+	}
+	if len(outside) > 0 {
+		return fmt.Errorf("%w(授权单号 %s),拒绝扫描: %s",
+			ErrOutOfScope, s.Authorization, strings.Join(outside, ", "))
+	}
+	return nil
+}
+
+// Excluded 返回始终排除的网段,由扫描器写入 zmap -b 和 masscan --excludefile 使用的排除列表
+func (s *Scope) Excluded() []netip.Prefix {
+	return slices.Clone(s.deny)
+}
@@ -0,0 +1,202 @@
+package scope
+
+import (
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeFile 把内容写入临时目录中的 name 文件并返回路径
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testScope 加载授权 10.0.0.0/16 和 2001:db8::/48 的授权范围,
+// 其中 10.0.5.0/24 和 2001:db8:0:1::/64 由 deny 排除,10.0.9.9 由拒绝列表文件排除
+func testScope(t *testing.T) *Scope {
+	t.Helper()
+	scopeFile := writeFile(t, "scope.json", `{
+		"owner": "网络运维组",
+		"authorization": "CHG-1",
+		"allow": ["10.0.0.0/16", "2001:db8::/48"],
+		"deny": ["10.0.5.0/24", "2001:db8:0:1::/64"]
+	}`)
+	denylist := writeFile(t, "deny.txt", "# 生产数据库\n10.0.9.9\n")
+	s, err := Load(scopeFile, denylist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestContains(t *testing.T) {
+	s := testScope(t)
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.0.0.1", true},
+		{"10.0.255.255", true},
+		{"10.1.0.1", false},
+		{"10.0.5.1", false},  // deny 中的网段
+		{"10.0.4.255", true}, // 紧邻排除网段
+		{"10.0.9.9", false},  // 拒绝列表文件
+		{"10.0.9.10", true},
+		{"::ffff:10.0.0.1", true},
+		{"::ffff:10.0.5.1", false},
+		{"2001:db8::1", true},
+		{"2001:db8:0:1::1", false},
+		{"2001:db8:1::1", false},
+		{"192.0.2.1", false},
+		{"not-an-ip", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := s.Contains(tt.ip); got != tt.want {
+			t.Errorf("Contains(%q) = %v, 期望 %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	s := testScope(t)
+	tests := []struct {
+		name    string
+		targets []string
+		outside []string
+	}{
+		{"单个地址", []string{"10.0.0.1/32"}, nil},
+		{"授权网段本身", []string{"10.0.0.0/16", "2001:db8::/48"}, nil},
+		{"授权网段内的子网", []string{"10.0.128.0/17", "2001:db8:0:2::/64"}, nil},
+		// 排除的网段在探测时跳过,不影响授权检查
+		{"包含排除网段", []string{"10.0.0.0/20"}, nil},
+		{"部分覆盖的网段", []string{"10.0.0.0/15"}, []string{"10.0.0.0/15"}},
+		{"部分覆盖的 IPv6 网段", []string{"2001:db8::/47"}, []string{"2001:db8::/47"}},
+		{"范围之外", []string{"10.0.0.1/32", "192.0.2.0/24", "2001:db9::1/128"}, []string{"192.0.2.0/24", "2001:db9::1/128"}},
+		{"不同协议", []string{"::a00:1/128"}, []string{"::a00:1/128"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var targets []netip.Prefix
+			for _, p := range tt.targets {
+				targets = append(targets, netip.MustParsePrefix(p))
+			}
+			err := s.Check(targets)
+			if tt.outside == nil {
+				if err != nil {
+					t.Fatalf("Check = %v, 期望通过", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrOutOfScope) {
+				t.Fatalf("Check = %v, 期望 ErrOutOfScope", err)
+			}
+			if !strings.Contains(err.Error(), "CHG-1") || !strings.HasSuffix(err.Error(), strings.Join(tt.outside, ", ")) {
+				t.Errorf("Check = %v, 期望列出 %v", err, tt.outside)
+			}
+		})
+	}
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		outer, p string
+		want     bool
+	}{
+		{"10.0.0.0/8", "10.1.2.0/24", true},
+		{"10.0.0.0/8", "10.0.0.0/8", true},
+		{"10.0.0.0/8", "10.0.0.0/7", false},
+		{"10.0.0.0/8", "11.0.0.0/24", false},
+		{"0.0.0.0/0", "192.0.2.1/32", true},
+		{"2001:db8::/32", "2001:db8:ffff::/48", true},
+		{"2001:db8::/32", "2001:db9::/48", false},
+		{"::/0", "10.0.0.1/32", false},
+		{"0.0.0.0/0", "::1/128", false},
+	}
+	for _, tt := range tests {
+		if got := covers(netip.MustParsePrefix(tt.outer), netip.MustParsePrefix(tt.p)); got != tt.want {
+			t.Errorf("covers(%s, %s) = %v, 期望 %v", tt.outer, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestReadTargetFile(t *testing.T) {
+	path := writeFile(t, "targets.txt", "# 办公网\n\n  10.0.0.7/24  \n192.0.2.1\n\t\n# IPv6\n2001:db8::/64\n2001:db8::1\n")
+	got, err := ReadTargetFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/24"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+		netip.MustParsePrefix("2001:db8::/64"),
+		netip.MustParsePrefix("2001:db8::1/128"),
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ReadTargetFile = %v, 期望 %v", got, want)
+	}
+
+	// 所有错误带行号一起报告
+	_, err = ReadTargetFile(writeFile(t, "bad.txt", "10.0.0.1\n10.0.0.0/33\n# 注释\nhost.example\n"))
+	if err == nil {
+		t.Fatal("期望返回错误")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "bad.txt:2: ") || !strings.Contains(lines[1], "bad.txt:4: ") {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := ReadTargetFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("文件不存在时应返回错误")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"缺少 owner", `{"authorization":"CHG-1","allow":["10.0.0.0/8"]}`, "缺少 owner"},
+		{"缺少授权单号", `{"owner":"a","allow":["10.0.0.0/8"]}`, "缺少 authorization"},
+		{"没有授权网段", `{"owner":"a","authorization":"CHG-1"}`, "未列出任何授权网段"},
+		{"allow 无效", `{"owner":"a","authorization":"CHG-1","allow":["10.0.0.0/33"]}`, "allow 条目无效"},
+		{"deny 无效", `{"owner":"a","authorization":"CHG-1","allow":["10.0.0.0/8"],"deny":["x"]}`, "deny 条目无效"},
+		{"格式错误", `{"owner":`, "解析授权范围文件"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeFile(t, "scope.json", tt.content), "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Load err = %v, 期望包含 %q", err, tt.want)
+			}
+		})
+	}
+	if _, err := Load("", ""); err == nil {
+		t.Error("未指定授权范围文件时应返回错误")
+	}
+}
+
+func TestExcluded(t *testing.T) {
+	s := testScope(t)
+	s.AddDeny(netip.MustParsePrefix("10.0.7.0/24"))
+	want := []string{"10.0.5.0/24", "2001:db8:0:1::/64", "10.0.9.9/32", "10.0.7.0/24"}
+	var got []string
+	for _, p := range s.Excluded() {
+		got = append(got, p.String())
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Excluded = %v, 期望 %v", got, want)
+	}
+	if !s.Contains("10.0.6.1") || s.Contains("10.0.7.1") {
+		t.Error("AddDeny 追加的网段应被排除")
+	}
+}
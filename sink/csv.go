@@ -0,0 +1,220 @@
+package sink
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/bench"
+	"github.com/aspnmy/ollama_scanner/scanner"
+)
+
+// CSV 将每个模型写为一行
+type CSV struct {
+	opts   Options
+	name   string
+	closer io.Closer
+	writer *csv.Writer
+}
+
+// NewCSV 创建 CSV 文件并写入表头
+func NewCSV(path string, opts Options) (*CSV, error) {
+	file, err := createFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建CSV文件失败: %w", err)
+	}
+	c, err := NewCSVWriter(file, opts)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	c.name, c.closer = file.Name(), file
+	return c, nil
+}
+
+// NewCSVWriter 将 CSV 写入 w 并写入表头,例如 HTTP 响应,Close 不会关闭 w
+func NewCSVWriter(w io.Writer, opts Options) (*CSV, error) {
+	c := &CSV{opts: opts, closer: io.NopCloser(nil), writer: csv.NewWriter(w)}
+	headers := []string{"IP地址", "主机名", "Ollama版本", "模型名称", "状态"}
+	if opts.Bench {
+		headers = append(headers, "首Token延迟(ms)", "Tokens/s", "模型类型", "嵌入维度", "嵌入测试(批量:延迟ms/inputs/s)",
+			"对话测试(轮次:首Token ms/tokens/s/提示tokens/提示处理ms)", "提示处理斜率(ms/千token)")
+	}
+	if opts.BenchStats {
+		headers = append(headers, "测试次数", "失败次数",
+			"首Token延迟P50(ms)", "首Token延迟P90(ms)", "首Token延迟P99(ms)", "首Token延迟标准差(ms)",
+			"Tokens/s P50", "Tokens/s P90", "Tokens/s P99", "Tokens/s标准差",
+			"冷启动首Token延迟(ms)", "冷启动加载时间(ms)")
+	}
+	if opts.Smoke {
+		headers = append(headers, "冒烟测试(通过/总数)", "未通过用例")
+	}
+	if opts.Policy {
+		headers = append(headers, "策略违规")
+	}
+	headers = append(headers, "已加载", "内存占用(MB)", "显存占用(MB)", "上下文长度", "卸载时间")
+	if opts.Details {
+		headers = append(headers, "许可证", "参数量", "量化", "最大上下文", "能力", "自定义系统提示词", "自定义模板")
+	}
+	headers = append(headers, "发现时间", "探测时间")
+	if opts.Bench {
+		headers = append(headers, "测试时间")
+	}
+	if err := c.writer.Write(headers); err != nil {
+		return nil, fmt.Errorf("写入CSV表头失败: %w", err)
+	}
+	return c, nil
+}
+
+// Name 返回 CSV 文件路径,写入 io.Writer 时为空
+func (c *CSV) Name() string {
+	return c.name
+}
+
+func (c *CSV) Write(res scanner.ScanResult) error {
+	for _, model := range res.Models {
+		record := []string{res.Address(), res.Hostname, res.Version, model.Name, model.StatusText()}
+		if c.opts.Bench {
+			record = append(record,
+				fmt.Sprintf("%.0f", model.FirstTokenDelay.Seconds()*1000),
+				fmt.Sprintf("%.1f", model.TokensPerSec), model.Type)
+			record = append(record, embedRecord(model.Embedding)...)
+			record = append(record, chatRecord(model.Chat)...)
+		}
+		if c.opts.BenchStats {
+			record = append(record, statsRecord(model.Bench)...)
+		}
+		if c.opts.Smoke {
+			record = append(record, smokeRecord(model.Smoke)...)
+		}
+		if c.opts.Policy {
+			var violations []string
+			for _, v := range model.Violations {
+				violations = append(violations, v.Message)
+			}
+			record = append(record, strings.Join(violations, "; "))
+		}
+		if m, ok := res.RunningModel(model.Name); ok {
+			record = append(record, "是",
+				fmt.Sprintf("%.1f", float64(m.Size)/1024/1024),
+				fmt.Sprintf("%.1f", float64(m.SizeVRAM)/1024/1024),
+				strconv.Itoa(m.ContextLength),
+				formatTime(m.ExpiresAt, time.RFC3339))
+		} else {
+			record = append(record, "否", "", "", "", "")
+		}
+		if c.opts.Details {
+			if d := model.Details; d != nil {
+				record = append(record, d.License, d.ParameterSize, d.Quantization,
+					strconv.Itoa(d.ContextLength), strings.Join(d.Capabilities, ","),
+					strconv.FormatBool(d.HasSystem), strconv.FormatBool(d.HasTemplate))
+			} else {
+				record = append(record, "", "", "", "", "", "", "")
+			}
+		}
+		record = append(record, formatTime(res.DiscoveredAt, time.RFC3339), formatTime(res.ProbedAt, time.RFC3339))
+		if c.opts.Bench {
+			record = append(record, formatTime(model.BenchmarkedAt, time.RFC3339))
+		}
+		if err := c.writer.Write(record); err != nil {
+			return fmt.Errorf("写入CSV失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// embedRecord 返回嵌入维度和每个批量大小的测试结果,例如 "1:12/83.3 8:40/200.0"
+func embedRecord(e *bench.EmbedResult) []string {
+	if e == nil {
+		return []string{"", ""}
+	}
+	var batches []string
+	for _, b := range e.Batches {
+		if b.Runs == 0 {
+			batches = append(batches, fmt.Sprintf("%d:%s", b.BatchSize, b.Status))
+			continue
+		}
+		batches = append(batches, fmt.Sprintf("%d:%s/%.1f", b.BatchSize, millis(b.Latency), b.InputsPerSec))
+	}
+	return []string{strconv.Itoa(e.Dimension), strings.Join(batches, " ")}
+}
+
+// chatRecord 返回对话测试每轮的结果和提示词处理时间的增长斜率,例如 "1:120/45.0/30/15 2:...",
+// 对话脚本执行多次时每轮以 执行次数.轮次 标记,例如 "2.1:..."
+func chatRecord(c *bench.ChatResult) []string {
+	if c == nil {
+		return []string{"", ""}
+	}
+	var turns []string
+	for _, t := range c.Turns {
+		label := strconv.Itoa(t.Turn)
+		if c.Runs > 1 {
+			label = fmt.Sprintf("%d.%d", t.Run, t.Turn)
+		}
+		if t.Status != "完成" {
+			turns = append(turns, fmt.Sprintf("%s:%s", label, t.Status))
+			continue
+		}
+		turns = append(turns, fmt.Sprintf("%s:%s/%.1f/%d/%s", label, millis(t.FirstTokenDelay),
+			t.TokensPerSec, t.PromptTokens, millis(t.PromptDuration)))
+	}
+	return []string{strings.Join(turns, " "), fmt.Sprintf("%.1f", c.PromptMsPer1K)}
+}
+
+// smokeRecord 返回冒烟测试的通过数和未通过的用例,例如 "3/4" 和 "capital:未通过"
+func smokeRecord(s *bench.SmokeResult) []string {
+	if s == nil {
+		return []string{"", ""}
+	}
+	var failed []string
+	for _, c := range s.Checks {
+		if !c.Passed {
+			failed = append(failed, c.Name+":"+c.Status)
+		}
+	}
+	return []string{fmt.Sprintf("%d/%d", s.Passed, s.Passed+s.Failed), strings.Join(failed, "; ")}
+}
+
+// statsRecord 返回统计值各列,没有统计值时为空
+func statsRecord(s *bench.Stats) []string {
+	if s == nil {
+		return make([]string, 12)
+	}
+	record := []string{strconv.Itoa(s.Runs), strconv.Itoa(s.Failed)}
+	if s.Runs > 0 {
+		record = append(record,
+			millis(s.FirstTokenP50), millis(s.FirstTokenP90), millis(s.FirstTokenP99), millis(s.FirstTokenStdDev),
+			fmt.Sprintf("%.1f", s.TokensPerSecP50), fmt.Sprintf("%.1f", s.TokensPerSecP90),
+			fmt.Sprintf("%.1f", s.TokensPerSecP99), fmt.Sprintf("%.1f", s.TokensPerSecStdDev))
+	} else {
+		record = append(record, make([]string, 8)...)
+	}
+	if s.Cold != nil && s.Cold.OK() {
+		record = append(record, millis(s.Cold.FirstTokenDelay), millis(s.Cold.LoadDuration))
+	} else {
+		record = append(record, "", "")
+	}
+	return record
+}
+
+// millis 将时长格式化为毫秒整数
+func millis(d time.Duration) string {
+	return fmt.Sprintf("%.0f", d.Seconds()*1000)
+}
+
+// Flush 将缓冲的记录写入文件
+func (c *CSV) Flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func (c *CSV) Close() error {
+	if err := c.Flush(); err != nil {
+		c.closer.Close()
+		return err
+	}
+	return c.closer.Close()
+}
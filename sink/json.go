@@ -0,0 +1,69 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aspnmy/ollama_scanner/scanner"
+)
+
+// JSON 以 JSON Lines 格式每行写入一条主机结果
+type JSON struct {
+	name    string
+	closer  io.Closer
+	encoder *json.Encoder
+}
+
+// NewJSON 创建 JSON Lines 输出文件
+func NewJSON(path string) (*JSON, error) {
+	file, err := createFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建JSON文件失败: %w", err)
+	}
+	return &JSON{name: file.Name(), closer: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// NewJSONWriter 将 JSON Lines 写入 w,Close 不会关闭 w
+func NewJSONWriter(w io.Writer) *JSON {
+	return &JSON{closer: io.NopCloser(nil), encoder: json.NewEncoder(w)}
+}
+
+// Name 返回 JSON 文件路径,写入 io.Writer 时为空
+func (j *JSON) Name() string {
+	return j.name
+}
+
+func (j *JSON) Write(res scanner.ScanResult) error {
+	if err := j.encoder.Encode(res); err != nil {
+		return fmt.Errorf("写入JSON失败: %w", err)
+	}
+	return nil
+}
+
+func (j *JSON) Close() error {
+	return j.closer.Close()
+}
+
+// ReadJSON 读取 JSON Lines 结果文件.扫描中断时最后一行可能不完整,此时保留已读取的结果
+func ReadJSON(path string) ([]scanner.ScanResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var results []scanner.ScanResult
+	dec := json.NewDecoder(f)
+	for {
+		var res scanner.ScanResult
+		if err := dec.Decode(&res); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return results, nil
+			}
+			return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+		}
+		results = append(results, res)
+	}
+}
@@ -0,0 +1,63 @@
+package sink
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/scanner"
+)
+
+// Samples 将性能测试的原始样本写为 CSV,每次生成请求一行,只测量一次的模型不输出
+type Samples struct {
+	name   string
+	file   io.Closer
+	writer *csv.Writer
+}
+
+// NewSamples 创建原始样本 CSV 文件并写入表头
+func NewSamples(path string) (*Samples, error) {
+	file, err := createFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建样本文件失败: %w", err)
+	}
+	s := &Samples{name: file.Name(), file: file, writer: csv.NewWriter(file)}
+	headers := []string{"IP地址", "主机名", "模型名称", "序号", "阶段", "开始时间",
+		"首Token延迟(ms)", "Tokens/s", "Token数", "加载时间(ms)", "状态"}
+	if err := s.writer.Write(headers); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("写入样本表头失败: %w", err)
+	}
+	return s, nil
+}
+
+// Name 返回样本文件路径
+func (s *Samples) Name() string {
+	return s.name
+}
+
+func (s *Samples) Write(res scanner.ScanResult) error {
+	for _, model := range res.Models {
+		for i, sample := range model.BenchSamples {
+			record := []string{res.Address(), res.Hostname, model.Name, strconv.Itoa(i + 1), sample.Phase,
+				formatTime(sample.StartedAt, time.RFC3339Nano), millis(sample.FirstTokenDelay),
+				fmt.Sprintf("%.2f", sample.TokensPerSec), strconv.Itoa(sample.Tokens),
+				millis(sample.LoadDuration), sample.Status}
+			if err := s.writer.Write(record); err != nil {
+				return fmt.Errorf("写入样本失败: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Samples) Close() error {
+	s.writer.Flush()
+	if err := s.writer.Error(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
@@ -0,0 +1,76 @@
+// Package sink 将扫描结果输出到终端、CSV 和 JSON 等目标.
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/scanner"
+)
+
+// Sink 接收扫描结果,Close 时刷新并释放资源
+type Sink interface {
+	Write(res scanner.ScanResult) error
+	Close() error
+}
+
+// LocalTimeLayout 为终端和报告中使用的本地化时间格式,机器可读的输出统一使用 RFC 3339
+const LocalTimeLayout = "2006-01-02 15:04:05 MST"
+
+// Options 控制输出中包含哪些列
+type Options struct {
+	Bench      bool // 输出性能测试结果
+	BenchStats bool // 输出多次性能测试的分位数、标准差和冷启动测量
+	Details    bool // 输出 /api/show 模型元数据
+	Smoke      bool // 输出冒烟测试结果
+	Policy     bool // 输出合规策略检查结果
+}
+
+// formatTime 格式化时间,零值输出为空字符串
+func formatTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// Multi 将结果依次写入多个输出目标
+type Multi []Sink
+
+func (m Multi) Write(res scanner.ScanResult) error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Write(res); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m Multi) Close() error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// createFile 创建输出文件,相对路径基于当前目录,并确保输出目录存在
+func createFile(path string) (*os.File, error) {
+	if !filepath.IsAbs(path) {
+		currentDir, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("获取当前目录失败: %w", err)
+		}
+		path = filepath.Join(currentDir, path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建输出目录失败: %w", err)
+	}
+	return os.Create(path)
+}
@@ -0,0 +1,139 @@
+package sink
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/scanner"
+)
+
+// Terminal 以树状格式将结果打印到终端
+type Terminal struct {
+	w    io.Writer
+	opts Options
+}
+
+// NewTerminal 创建终端输出
+func NewTerminal(w io.Writer, opts Options) *Terminal {
+	return &Terminal{w: w, opts: opts}
+}
+
+func (t *Terminal) Write(res scanner.ScanResult) error {
+	w := t.w
+	fmt.Fprintf(w, "\nIP地址: %s\n", res.Address())
+	if res.Hostname != "" {
+		fmt.Fprintf(w, "主机名: %s\n", res.Hostname)
+	}
+	if res.Version != "" {
+		fmt.Fprintf(w, "Ollama版本: %s\n", res.Version)
+	}
+	fmt.Fprintf(w, "发现时间: %s 探测时间: %s\n",
+		formatTime(res.DiscoveredAt, LocalTimeLayout), formatTime(res.ProbedAt, LocalTimeLayout))
+	fmt.Fprintln(w, strings.Repeat("-", 50))
+	for _, model := range res.Models {
+		fmt.Fprintf(w, "├─ 模型: %-25s\n", model.Name)
+		if t.opts.Bench {
+			fmt.Fprintf(w, "│ ├─ 状态: %s\n", model.StatusText())
+			if !model.BenchmarkedAt.IsZero() {
+				fmt.Fprintf(w, "│ ├─ 测试时间: %s\n", formatTime(model.BenchmarkedAt, LocalTimeLayout))
+			}
+			if e := model.Embedding; e != nil {
+				fmt.Fprintf(w, "│ ├─ 类型: 嵌入模型 维度: %d\n", e.Dimension)
+				for i, b := range e.Batches {
+					branch := "├─"
+					if i == len(e.Batches)-1 {
+						branch = "└─"
+					}
+					if b.Runs == 0 {
+						fmt.Fprintf(w, "│ %s 批量 %d: %s\n", branch, b.BatchSize, b.Status)
+						continue
+					}
+					fmt.Fprintf(w, "│ %s 批量 %d: 延迟 %v %.1f inputs/s\n", branch, b.BatchSize,
+						b.Latency.Round(time.Millisecond), b.InputsPerSec)
+				}
+				if len(e.Batches) == 0 {
+					fmt.Fprintf(w, "│ └─ 批量测试: 无结果\n")
+				}
+			} else if c := model.Chat; c != nil {
+				fmt.Fprintf(w, "│ ├─ 首Token延迟: %v 生成速度: %.1f tokens/s(各轮中位数)\n",
+					model.FirstTokenDelay.Round(time.Millisecond), model.TokensPerSec)
+				for _, turn := range c.Turns {
+					if turn.Status != "完成" {
+						fmt.Fprintf(w, "│ ├─ %s: %s\n", turn.Label(c.Runs), turn.Status)
+						continue
+					}
+					fmt.Fprintf(w, "│ ├─ %s(%d 条消息): 首Token %v %.1f tokens/s 提示词 %d tokens 处理 %v\n",
+						turn.Label(c.Runs), turn.Messages, turn.FirstTokenDelay.Round(time.Millisecond), turn.TokensPerSec,
+						turn.PromptTokens, turn.PromptDuration.Round(time.Millisecond))
+				}
+				fmt.Fprintf(w, "│ └─ 提示词处理时间随上下文增长: %.1f ms/千token\n", c.PromptMsPer1K)
+			} else {
+				fmt.Fprintf(w, "│ ├─ 首Token延迟: %v\n", model.FirstTokenDelay.Round(time.Millisecond))
+				if s := model.Bench; s != nil && s.Runs > 0 {
+					fmt.Fprintf(w, "│ ├─ 生成速度: %.1f tokens/s\n", model.TokensPerSec)
+					fmt.Fprintf(w, "│ ├─ 测试次数: %d 失败: %d\n", s.Runs, s.Failed)
+					fmt.Fprintf(w, "│ ├─ 首Token延迟 P50/P90/P99: %v / %v / %v ±%v\n",
+						s.FirstTokenP50.Round(time.Millisecond), s.FirstTokenP90.Round(time.Millisecond),
+						s.FirstTokenP99.Round(time.Millisecond), s.FirstTokenStdDev.Round(time.Millisecond))
+					cold := s.Cold != nil && s.Cold.OK()
+					branch := "└─"
+					if cold {
+						branch = "├─"
+					}
+					fmt.Fprintf(w, "│ %s 生成速度 P50/P90/P99: %.1f / %.1f / %.1f ±%.1f tokens/s\n", branch,
+						s.TokensPerSecP50, s.TokensPerSecP90, s.TokensPerSecP99, s.TokensPerSecStdDev)
+					if cold {
+						fmt.Fprintf(w, "│ └─ 冷启动首Token延迟: %v 加载时间: %v\n",
+							s.Cold.FirstTokenDelay.Round(time.Millisecond), s.Cold.LoadDuration.Round(time.Millisecond))
+					}
+				} else {
+					fmt.Fprintf(w, "│ └─ 生成速度: %.1f tokens/s\n", model.TokensPerSec)
+				}
+			}
+		} else {
+			fmt.Fprintf(w, "│ └─ 状态: %s\n", model.StatusText())
+		}
+		if s := model.Smoke; s != nil {
+			fmt.Fprintf(w, "│   ├─ 冒烟测试: %d/%d 通过\n", s.Passed, s.Passed+s.Failed)
+			for _, c := range s.Checks {
+				if c.Passed {
+					continue
+				}
+				if c.Response != "" {
+					fmt.Fprintf(w, "│   │ ✗ %s: %s 回复: %q\n", c.Name, c.Status, c.Response)
+				} else {
+					fmt.Fprintf(w, "│   │ ✗ %s: %s\n", c.Name, c.Status)
+				}
+			}
+		}
+		for _, v := range model.Violations {
+			fmt.Fprintf(w, "│   ├─ ⛔ 策略违规: %s\n", v.Message)
+		}
+		if d := model.Details; d != nil {
+			fmt.Fprintf(w, "│   ├─ 许可证: %s\n", d.License)
+			fmt.Fprintf(w, "│   ├─ 参数量: %s 量化: %s\n", d.ParameterSize, d.Quantization)
+			fmt.Fprintf(w, "│   ├─ 最大上下文: %d\n", d.ContextLength)
+			fmt.Fprintf(w, "│   ├─ 能力: %s\n", strings.Join(d.Capabilities, ","))
+			fmt.Fprintf(w, "│   └─ 自定义系统提示词: %v 自定义模板: %v\n", d.HasSystem, d.HasTemplate)
+		}
+		fmt.Fprintln(w, strings.Repeat("-", 50))
+	}
+	if len(res.Running) > 0 {
+		fmt.Fprintln(w, "已加载的模型:")
+		for _, m := range res.Running {
+			fmt.Fprintf(w, "├─ 模型: %-25s\n", m.Name)
+			fmt.Fprintf(w, "│ ├─ 内存占用: %.1f MB\n", float64(m.Size)/1024/1024)
+			fmt.Fprintf(w, "│ ├─ 显存占用: %.1f MB\n", float64(m.SizeVRAM)/1024/1024)
+			fmt.Fprintf(w, "│ ├─ 上下文长度: %d\n", m.ContextLength)
+			fmt.Fprintf(w, "│ └─ 卸载时间: %s\n", formatTime(m.ExpiresAt, LocalTimeLayout))
+		}
+		fmt.Fprintln(w, strings.Repeat("-", 50))
+	}
+	return nil
+}
+
+func (t *Terminal) Close() error {
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package store
+
+// pidAlive 在无法检查进程的平台上假定进程仍然存在,不把其他进程的运行误判为失败
+func pidAlive(pid int) bool {
+	return true
+}
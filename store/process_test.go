@@ -0,0 +1,21 @@
+package store
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestProcessAlive(t *testing.T) {
+	if !processAlive(os.Getpid()) || processAlive(0) || processAlive(-1) {
+		t.Fatal("当前进程应存在,无效 PID 不存在")
+	}
+	// 已退出并被回收的子进程不再存在
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if processAlive(cmd.Process.Pid) {
+		t.Errorf("已退出的进程 %d 不应存在", cmd.Process.Pid)
+	}
+}
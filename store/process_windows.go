@@ -0,0 +1,25 @@
+//go:build windows
+
+package store
+
+import (
+	"errors"
+	"syscall"
+)
+
+// stillActive 为进程尚未退出时 GetExitCodeProcess 返回的退出码
+const stillActive = 259
+
+// pidAlive 打开进程句柄并检查其是否已经退出,无权打开时进程同样存在
+func pidAlive(pid int) bool {
+	h, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return errors.Is(err, syscall.ERROR_ACCESS_DENIED)
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
@@ -0,0 +1,285 @@
+// Package store 在本地目录中保存扫描任务的运行记录和每次运行的结果,
+// 供守护进程和 API 查询.运行记录以追加方式写入 runs.jsonl,同一 ID 以最后一条为准.
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aspnmy/ollama_scanner/scanner"
+	"github.com/aspnmy/ollama_scanner/sink"
+)
+
+// RunStatus 为一次运行的状态
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "running"
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+	RunCancelled RunStatus = "cancelled"
+	// RunSkipped 表示上一次运行尚未结束,本次调度被跳过
+	RunSkipped RunStatus = "skipped"
+)
+
+// Run 为一次扫描任务的运行记录
+type Run struct {
+	ID         string    `json:"id"`
+	Job        string    `json:"job"`
+	Status     RunStatus `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	Hosts      int       `json:"hosts"`
+	Models     int       `json:"models"`
+	Error      string    `json:"error,omitempty"`
+	// PID 为执行该运行的进程,用于识别进程退出后遗留的 running 记录
+	PID int `json:"pid,omitempty"`
+}
+
+// Store 为基于目录的结果存储,可以被多个 goroutine 同时使用.
+// 守护进程和 API 服务可以共用同一目录,查询时会读入其他进程追加的记录.
+type Store struct {
+	dir string
+
+	mu     sync.Mutex
+	runs   []Run
+	index  map[string]int
+	offset int64 // runs.jsonl 中已读取的字节数
+	log    *os.File
+}
+
+// Open 打开或创建 dir 下的结果存储.执行进程已经退出但仍处于 running 状态的记录被标记为失败.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "results"), 0755); err != nil {
+		return nil, fmt.Errorf("创建结果存储目录失败: %w", err)
+	}
+	s := &Store{dir: dir, index: map[string]int{}}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	log, err := os.OpenFile(s.runsPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开运行记录失败: %w", err)
+	}
+	s.log = log
+
+	for _, r := range s.runs {
+		if r.Status == RunRunning && !processAlive(r.PID) {
+			r.Status = RunFailed
+			r.Error = "进程在运行期间退出"
+			if err := s.put(r); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *Store) runsPath() string {
+	return filepath.Join(s.dir, "runs.jsonl")
+}
+
+func (s *Store) resultsPath(id string) string {
+	return filepath.Join(s.dir, "results", id+".jsonl")
+}
+
+// load 从上次读取的位置继续读取运行记录,不完整的最后一行留到下次读取
+func (s *Store) load() error {
+	f, err := os.Open(s.runsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取运行记录失败: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(s.offset, io.SeekStart); err != nil {
+		return fmt.Errorf("读取运行记录失败: %w", err)
+	}
+
+	rd := bufio.NewReader(f)
+	for {
+		line, err := rd.ReadBytes('\n')
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("读取运行记录失败: %w", err)
+		}
+		s.offset += int64(len(line))
+		var r Run
+		if err := json.Unmarshal(line, &r); err != nil || r.ID == "" {
+			continue
+		}
+		s.set(r)
+	}
+}
+
+// refresh 读入其他进程追加的记录,失败时继续使用内存中的记录
+func (s *Store) refresh() {
+	s.load()
+}
+
+// processAlive 判断执行运行的进程是否仍然存在,具体检查方式见 pidAlive
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	if pid == os.Getpid() {
+		return true
+	}
+	return pidAlive(pid)
+}
+
+func (s *Store) set(r Run) {
+	if i, ok := s.index[r.ID]; ok {
+		s.runs[i] = r
+		return
+	}
+	s.index[r.ID] = len(s.runs)
+	s.runs = append(s.runs, r)
+}
+
+// put 追加一条运行记录,调用方需持有锁或处于初始化阶段
+func (s *Store) put(r Run) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := s.log.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入运行记录失败: %w", err)
+	}
+	s.set(r)
+	return nil
+}
+
+// newID 生成以开始时间和任务名组成的运行 ID,冲突时追加序号
+func (s *Store) newID(job string, at time.Time) string {
+	base := at.Format("20060102T150405") + "-" + job
+	id := base
+	for n := 2; ; n++ {
+		if _, ok := s.index[id]; !ok {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// Begin 记录一次运行的开始,返回的 Recording 接收结果并在 Finish 时更新运行记录
+func (s *Store) Begin(job string, at time.Time) (*Recording, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refresh()
+	run := Run{ID: s.newID(job, at), Job: job, Status: RunRunning, StartedAt: at, PID: os.Getpid()}
+	out, err := sink.NewJSON(s.resultsPath(run.ID))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.put(run); err != nil {
+		out.Close()
+		return nil, err
+	}
+	return &Recording{store: s, run: run, out: out}, nil
+}
+
+// Skip 记录一次因上一次运行未结束而被跳过的调度
+func (s *Store) Skip(job string, at time.Time, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+	return s.put(Run{ID: s.newID(job, at), Job: job, Status: RunSkipped, StartedAt: at, FinishedAt: at, Error: reason})
+}
+
+// Runs 返回运行记录,job 不为空时只返回该任务的记录,按开始时间从新到旧排列
+func (s *Store) Runs(job string) []Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+
+	var runs []Run
+	for _, r := range s.runs {
+		if job == "" || r.Job == job {
+			runs = append(runs, r)
+		}
+	}
+	slices.Reverse(runs)
+	return runs
+}
+
+// Run 按 ID 查找运行记录
+func (s *Store) Run(id string) (Run, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh()
+	i, ok := s.index[id]
+	if !ok {
+		return Run{}, false
+	}
+	return s.runs[i], true
+}
+
+// Results 读取一次运行写入的全部主机结果
+func (s *Store) Results(id string) ([]scanner.ScanResult, error) {
+	if _, ok := s.Run(id); !ok {
+		return nil, fmt.Errorf("运行记录不存在: %s", id)
+	}
+	results, err := sink.ReadJSON(s.resultsPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取扫描结果失败: %w", err)
+	}
+	return results, nil
+}
+
+// Close 关闭运行记录文件
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.Close()
+}
+
+// Recording 为一次进行中的运行,实现 sink.Sink
+type Recording struct {
+	store *Store
+	run   Run
+	out   *sink.JSON
+}
+
+// ID 返回运行 ID
+func (r *Recording) ID() string {
+	return r.run.ID
+}
+
+func (r *Recording) Write(res scanner.ScanResult) error {
+	r.run.Hosts++
+	r.run.Models += len(res.Models)
+	return r.out.Write(res)
+}
+
+// Close 关闭结果文件,运行记录的状态由 Finish 更新
+func (r *Recording) Close() error {
+	return r.out.Close()
+}
+
+// Finish 根据 status 和 err 更新运行记录
+func (r *Recording) Finish(status RunStatus, err error, at time.Time) error {
+	r.run.Status = status
+	r.run.FinishedAt = at
+	if err != nil {
+		r.run.Error = err.Error()
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return r.store.put(r.run)
+}
@@ -0,0 +1,269 @@
+package target
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 导入文件格式
+const (
+	FormatNmap = "nmap"
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// DefaultColumn 为 CSV 中默认的地址列名,也是 JSON 对象中默认的地址字段
+const DefaultColumn = "ip"
+
+// DetectFormat 根据扩展名判断导入文件格式,无法判断时返回空字符串
+func DetectFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xml":
+		return FormatNmap
+	case ".csv":
+		return FormatCSV
+	case ".json":
+		return FormatJSON
+	}
+	return ""
+}
+
+// Import 从 Nmap XML、CSV 或 JSON 清单导入单个主机目标.format 为空时按扩展名判断,
+// column 为 CSV 的地址列名或 JSON 对象的地址字段,为空时使用 ip.
+// 导入的每个值与输入文件的一行格式相同,但只能是单个地址或主机名.
+// port 为扫描端口,Nmap XML 中只导入该端口和被识别为 Ollama 的开放端口.
+func Import(ctx context.Context, path string, format string, column string, port int) ([]Target, error) {
+	if format == "" {
+		format = DetectFormat(path)
+	}
+	if column == "" {
+		column = DefaultColumn
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []entry
+	switch format {
+	case FormatNmap:
+		entries, err = readNmap(file, port)
+	case FormatCSV:
+		entries, err = readCSV(file, column)
+	case FormatJSON:
+		entries, err = readJSON(file, column)
+	default:
+		return nil, fmt.Errorf("不支持的导入格式: %q,可选 nmap、csv 或 json", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+
+	var (
+		targets  []Target
+		problems []string
+	)
+	for _, e := range entries {
+		ts, err := parseLine(ctx, e.value)
+		if err == nil && !singleHost(ts) {
+			err = fmt.Errorf("导入的目标必须是单个地址或主机名: %q", e.value)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s:%s: %v", path, e.pos, err))
+			continue
+		}
+		for i := range ts {
+			ts[i].Line = e.line
+			if e.host != "" && ts[i].Host == "" {
+				ts[i].Host = e.host
+			}
+		}
+		targets = append(targets, ts...)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	return normalize(targets), nil
+}
+
+// singleHost 判断一行的解析结果是否为单个主机:一个地址,或同一主机名解析出的多个地址
+func singleHost(ts []Target) bool {
+	for _, t := range ts {
+		if !t.Single() || (len(ts) > 1 && t.Host == "") {
+			return false
+		}
+	}
+	return true
+}
+
+// entry 为清单中的一个地址,pos 为报告错误时使用的位置
+type entry struct {
+	value string
+	host  string
+	line  int
+	pos   string
+}
+
+// nmapRun 为 Nmap -oX 输出中用到的部分
+type nmapRun struct {
+	Hosts []struct {
+		Status struct {
+			State string `xml:"state,attr"`
+		} `xml:"status"`
+		Addresses []struct {
+			Addr     string `xml:"addr,attr"`
+			AddrType string `xml:"addrtype,attr"`
+		} `xml:"address"`
+		Hostnames []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"hostnames>hostname"`
+		Ports []struct {
+			Protocol string `xml:"protocol,attr"`
+			PortID   int    `xml:"portid,attr"`
+			State    struct {
+				State string `xml:"state,attr"`
+			} `xml:"state"`
+			Service struct {
+				Name      string `xml:"name,attr"`
+				Product   string `xml:"product,attr"`
+				ExtraInfo string `xml:"extrainfo,attr"`
+			} `xml:"service"`
+			Scripts []struct {
+				Output string `xml:"output,attr"`
+			} `xml:"script"`
+		} `xml:"ports>port"`
+	} `xml:"host"`
+}
+
+// readNmap 读取 Nmap XML 中处于 up 状态的主机上可能是 Ollama 的开放 TCP 端口:
+// 扫描端口,以及服务识别或脚本输出中提到 Ollama 的端口.主机名取 Nmap 记录的第一个名称.
+func readNmap(r io.Reader, port int) ([]entry, error) {
+	var run nmapRun
+	if err := xml.NewDecoder(r).Decode(&run); err != nil {
+		return nil, err
+	}
+	var entries []entry
+	for i, h := range run.Hosts {
+		if h.Status.State != "" && h.Status.State != "up" {
+			continue
+		}
+		var addr netip.Addr
+		for _, a := range h.Addresses {
+			if a.AddrType == "ipv4" || a.AddrType == "ipv6" {
+				addr, _ = netip.ParseAddr(a.Addr)
+				break
+			}
+		}
+		if !addr.IsValid() {
+			continue
+		}
+		var host string
+		if len(h.Hostnames) > 0 {
+			host = h.Hostnames[0].Name
+		}
+		for _, p := range h.Ports {
+			if p.Protocol != "tcp" || p.State.State != "open" {
+				continue
+			}
+			info := []string{p.Service.Name, p.Service.Product, p.Service.ExtraInfo}
+			for _, script := range p.Scripts {
+				info = append(info, script.Output)
+			}
+			if p.PortID != port && !mentionsOllama(info) {
+				continue
+			}
+			entries = append(entries, entry{
+				value: netip.AddrPortFrom(addr, uint16(p.PortID)).String(),
+				host:  host,
+				pos:   "host " + strconv.Itoa(i+1),
+			})
+		}
+	}
+	return entries, nil
+}
+
+// mentionsOllama 判断 Nmap 的服务信息中是否提到 Ollama,不区分大小写
+func mentionsOllama(info []string) bool {
+	for _, s := range info {
+		if strings.Contains(strings.ToLower(s), "ollama") {
+			return true
+		}
+	}
+	return false
+}
+
+// readCSV 读取带表头的 CSV 中 column 列的地址,列名不区分大小写,空值被忽略
+func readCSV(r io.Reader, column string) ([]entry, error) {
+	rd := csv.NewReader(r)
+	rd.FieldsPerRecord = -1
+	header, err := rd.Read()
+	if err != nil {
+		return nil, fmt.Errorf("读取表头失败: %w", err)
+	}
+	idx := -1
+	for i, name := range header {
+		// 去掉 Excel 导出的 UTF-8 BOM
+		if strings.EqualFold(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")), column) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return nil, fmt.Errorf("表头中没有 %q 列", column)
+	}
+
+	var entries []entry
+	for {
+		record, err := rd.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := rd.FieldPos(0)
+		if idx >= len(record) || strings.TrimSpace(record[idx]) == "" {
+			continue
+		}
+		entries = append(entries, entry{value: strings.TrimSpace(record[idx]), line: line, pos: strconv.Itoa(line)})
+	}
+}
+
+// readJSON 读取地址字符串数组,或对象数组中 field 字段的地址
+func readJSON(r io.Reader, field string) ([]entry, error) {
+	var items []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, err
+	}
+	var entries []entry
+	for i, item := range items {
+		pos := fmt.Sprintf("[%d]", i)
+		var value string
+		if err := json.Unmarshal(item, &value); err != nil {
+			var obj map[string]any
+			if err := json.Unmarshal(item, &obj); err != nil {
+				return nil, fmt.Errorf("%s: 元素必须是字符串或对象", pos)
+			}
+			v, ok := obj[field].(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: 对象中没有字符串字段 %q", pos, field)
+			}
+			value = v
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			entries = append(entries, entry{value: value, line: i + 1, pos: pos})
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,159 @@
+package target
+
+import (
+	"cmp"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const nmapXML = `<?xml version="1.0"?>
+<nmaprun scanner="nmap">
+  <host>
+    <status state="up"/>
+    <address addr="192.0.2.10" addrtype="ipv4"/>
+    <address addr="00:11:22:33:44:55" addrtype="mac"/>
+    <hostnames><hostname name="gpu01.lab" type="PTR"/><hostname name="gpu01" type="user"/></hostnames>
+    <ports>
+      <port protocol="tcp" portid="11434"><state state="open"/></port>
+      <port protocol="tcp" portid="22"><state state="open"/><service name="ssh" product="OpenSSH"/></port>
+      <port protocol="tcp" portid="3306"><state state="closed"/></port>
+      <port protocol="tcp" portid="8080"><state state="open"/><service name="http" product="Ollama"/></port>
+      <port protocol="tcp" portid="8443"><state state="open"/><service name="http" tunnel="ssl"/>
+        <script id="http-title" output="Site doesn&apos;t have a title (text/plain). Ollama is running"/></port>
+      <port protocol="tcp" portid="9000"><state state="open"/><service name="http" product="nginx"/></port>
+      <port protocol="udp" portid="53"><state state="open"/></port>
+    </ports>
+  </host>
+  <host>
+    <status state="down"/>
+    <address addr="192.0.2.11" addrtype="ipv4"/>
+    <ports><port protocol="tcp" portid="11434"><state state="open"/></port></ports>
+  </host>
+  <host>
+    <status state="up"/>
+    <address addr="2001:db8::5" addrtype="ipv6"/>
+    <ports><port protocol="tcp" portid="11434"><state state="open"/></port></ports>
+  </host>
+</nmaprun>`
+
+// masscan -oX 的输出没有 status 和 hostnames
+const masscanXML = `<?xml version="1.0"?>
+<nmaprun scanner="masscan">
+  <host endtime="1700000000"><address addr="198.51.100.7" addrtype="ipv4"/>
+    <ports><port protocol="tcp" portid="11434"><state state="open" reason="syn-ack"/></port></ports></host>
+  <host endtime="1700000001"><address addr="198.51.100.3" addrtype="ipv4"/>
+    <ports><port protocol="tcp" portid="11434"><state state="open" reason="syn-ack"/></port></ports></host>
+</nmaprun>`
+
+// masscan -oJ 的输出为对象数组
+const masscanJSON = `[
+{"ip": "198.51.100.7", "timestamp": "1700000000", "ports": [{"port": 11434, "proto": "tcp", "status": "open"}]},
+{"ip": "198.51.100.3", "timestamp": "1700000001", "ports": [{"port": 11434, "proto": "tcp", "status": "open"}]}
+]`
+
+// writeImport 把内容写入临时目录中的 name 文件并返回路径
+func writeImport(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		format  string
+		column  string
+		port    int
+		want    []string
+	}{
+		{"nmap", "scan.xml", nmapXML, "", "", 0, []string{"gpu01.lab(192.0.2.10:8080)", "gpu01.lab(192.0.2.10:8443)",
+			"gpu01.lab(192.0.2.10:11434)", "[2001:db8::5]:11434"}},
+		{"nmap 指定扫描端口", "scan.xml", nmapXML, "", "", 22, []string{"gpu01.lab(192.0.2.10:22)",
+			"gpu01.lab(192.0.2.10:8080)", "gpu01.lab(192.0.2.10:8443)"}},
+		{"masscan XML", "masscan.xml", masscanXML, "", "", 0, []string{"198.51.100.3:11434", "198.51.100.7:11434"}},
+		{"masscan JSON", "masscan.json", masscanJSON, "", "", 0, []string{"198.51.100.3", "198.51.100.7"}},
+		{"CSV", "hosts.csv", "\ufeffHostname,IP\ngpu01,10.0.0.2\ngpu02,\ngpu03,10.0.0.1:8080\n", "", "", 0, []string{"10.0.0.2", "10.0.0.1:8080"}},
+		{"CSV 指定列", "hosts.csv", "name,addr\na,10.0.0.2\n", "", "addr", 0, []string{"10.0.0.2"}},
+		{"JSON 字符串数组", "hosts.json", `["10.0.0.2", " ", "10.0.0.1", "10.0.0.1"]`, "", "", 0, []string{"10.0.0.1", "10.0.0.2"}},
+		{"JSON 对象数组指定字段", "hosts.json", `[{"address": "10.0.0.2"}]`, "", "address", 0, []string{"10.0.0.2"}},
+		{"指定格式", "hosts.txt", `["10.0.0.2"]`, FormatJSON, "", 0, []string{"10.0.0.2"}},
+		{"主机名", "hosts.json", `["localhost"]`, "", "", 0, []string{"localhost(127.0.0.1)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeImport(t, tt.file, tt.content)
+			targets, err := Import(context.Background(), path, tt.format, tt.column, cmp.Or(tt.port, 11434))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := targetStrings(targets)
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Fatalf("Import = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportLineNumbers(t *testing.T) {
+	path := writeImport(t, "hosts.csv", "ip\n10.0.0.2\n\n10.0.0.1\n")
+	targets, err := Import(context.Background(), path, "", "", 11434)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 || targets[0].Line != 4 || targets[1].Line != 2 {
+		t.Fatalf("Targets = %+v", targets)
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		format  string
+		want    string
+	}{
+		{"未知格式", "hosts.txt", "10.0.0.1", "", "不支持的导入格式"},
+		{"XML 格式错误", "scan.xml", "<nmaprun><host>", "", "解析"},
+		{"CSV 缺少列", "hosts.csv", "host\n10.0.0.1\n", "", `表头中没有 "ip" 列`},
+		{"CSV 中的网段", "hosts.csv", "ip\n10.0.0.1\n10.0.0.0/24\n", "", ":3: 导入的目标必须是单个地址或主机名"},
+		{"JSON 中的地址范围", "hosts.json", `["10.0.0.1-10.0.0.3"]`, "", ":[0]: 导入的目标必须是单个地址或主机名"},
+		{"两个地址的范围", "hosts.json", `["10.0.0.5-10.0.0.6"]`, "", ":[0]: 导入的目标必须是单个地址或主机名"},
+		{"JSON 元素类型错误", "hosts.json", `[1]`, "", "元素必须是字符串或对象"},
+		{"JSON 对象缺少字段", "hosts.json", `[{"host": "a"}]`, "", `对象中没有字符串字段 "ip"`},
+		{"JSON 不是数组", "hosts.json", `{"ip": "10.0.0.1"}`, "", "解析"},
+		{"无效地址", "hosts.json", `["10.0.0.300"]`, "", "无法解析地址"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeImport(t, tt.file, tt.content)
+			_, err := Import(context.Background(), path, tt.format, "", 11434)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Import err = %v, 期望包含 %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]string{
+		"scan.xml":   FormatNmap,
+		"HOSTS.CSV":  FormatCSV,
+		"hosts.json": FormatJSON,
+		"ip.txt":     "",
+		"noext":      "",
+	}
+	for path, want := range tests {
+		if got := DetectFormat(path); got != want {
+			t.Errorf("DetectFormat(%q) = %q, 期望 %q", path, got, want)
+		}
+	}
+}
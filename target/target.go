@@ -0,0 +1,263 @@
+// Package target 解析扫描输入文件,支持网段、地址范围、单个地址、主机名、
+// 带端口的目标以及 # 注释,并对解析结果归一化和去重.
+package target
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Target 为输入文件中的一个扫描目标
+type Target struct {
+	// Prefix 为目标网段,单个地址和主机名解析出的地址为 /32 或 /128
+	Prefix netip.Prefix
+	// Host 为输入中的主机名,直接给出地址时为空
+	Host string
+	// Port 为输入中指定的端口,为 0 时使用扫描端口
+	Port int
+	// Line 为目标在输入文件中的行号
+	Line int
+}
+
+// Single 判断目标是否为单个地址
+func (t Target) Single() bool {
+	return t.Prefix.IsSingleIP()
+}
+
+func (t Target) String() string {
+	s := t.Prefix.String()
+	if t.Single() {
+		s = t.Prefix.Addr().String()
+	}
+	if t.Port != 0 {
+		s = net.JoinHostPort(s, strconv.Itoa(t.Port))
+	}
+	if t.Host != "" {
+		s = fmt.Sprintf("%s(%s)", t.Host, s)
+	}
+	return s
+}
+
+// ReadFile 读取并解析目标文件,主机名在读取时解析
+func ReadFile(ctx context.Context, path string) ([]Target, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(ctx, file, path)
+}
+
+// Read 逐行解析目标,所有格式错误和无法解析的主机名一起返回,
+// 每个问题以 "name:行号:" 开头.返回的目标已排序,被同端口的其他目标完整覆盖的条目会被去掉.
+func Read(ctx context.Context, r io.Reader, name string) ([]Target, error) {
+	var (
+		targets  []Target
+		problems []string
+		lineNo   int
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNo++
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		ts, err := parseLine(ctx, line)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s:%d: %v", name, lineNo, err))
+			continue
+		}
+		for i := range ts {
+			ts[i].Line = lineNo
+		}
+		targets = append(targets, ts...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	return normalize(targets), nil
+}
+
+// parseLine 依次尝试地址范围、网段、地址、地址:端口、主机名[:端口]
+func parseLine(ctx context.Context, s string) ([]Target, error) {
+	if from, to, ok := strings.Cut(s, "-"); ok {
+		a, errA := parseAddr(strings.TrimSpace(from))
+		b, errB := parseAddr(strings.TrimSpace(to))
+		if errA == nil && errB == nil {
+			return parseRange(a, b)
+		}
+	}
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if h, _, serr := net.SplitHostPort(s); err != nil && serr == nil && strings.Contains(h, "/") {
+			return nil, fmt.Errorf("只有单个地址或主机名可以指定端口: %q", s)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("无法解析网段 %q: %w", s, err)
+		}
+		if p.Addr().Zone() != "" {
+			return nil, fmt.Errorf("不支持带区域的 IPv6 地址: %q", s)
+		}
+		return []Target{{Prefix: p.Masked()}}, nil
+	}
+	if addr, err := parseAddr(s); err == nil {
+		return []Target{{Prefix: netip.PrefixFrom(addr, addr.BitLen())}}, nil
+	}
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		addr, err := parseAddr(ap.Addr().String())
+		if err != nil {
+			return nil, err
+		}
+		if ap.Port() == 0 {
+			return nil, fmt.Errorf("端口必须在 1-65535 之间: %q", s)
+		}
+		return []Target{{Prefix: netip.PrefixFrom(addr, addr.BitLen()), Port: int(ap.Port())}}, nil
+	}
+
+	host, port := s, 0
+	if h, p, err := net.SplitHostPort(s); err == nil {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > 65535 {
+			return nil, fmt.Errorf("端口必须在 1-65535 之间: %q", s)
+		}
+		host, port = h, n
+		if strings.Contains(host, "-") && looksNumeric(host) {
+			return nil, fmt.Errorf("只有单个地址或主机名可以指定端口: %q", s)
+		}
+	}
+	if !validHostname(host) {
+		return nil, fmt.Errorf("无法识别的目标 %q", s)
+	}
+	if looksNumeric(host) {
+		return nil, fmt.Errorf("无法解析地址 %q", host)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, fmt.Errorf("解析主机名 %s 失败: %w", host, err)
+	}
+	var targets []Target
+	for _, addr := range addrs {
+		addr = addr.Unmap().WithZone("")
+		targets = append(targets, Target{Prefix: netip.PrefixFrom(addr, addr.BitLen()), Host: host, Port: port})
+	}
+	return targets, nil
+}
+
+// parseAddr 解析单个地址,IPv4 映射地址转换为 IPv4,拒绝带区域的 IPv6 地址
+func parseAddr(s string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if addr.Zone() != "" {
+		return netip.Addr{}, fmt.Errorf("不支持带区域的 IPv6 地址: %q", s)
+	}
+	return addr.Unmap(), nil
+}
+
+// parseRange 将 from-to 地址范围拆分为最少数量的网段
+func parseRange(from netip.Addr, to netip.Addr) ([]Target, error) {
+	if from.Is4() != to.Is4() {
+		return nil, fmt.Errorf("地址范围 %s-%s 的起止地址不属于同一协议", from, to)
+	}
+	if from.Compare(to) > 0 {
+		return nil, fmt.Errorf("地址范围 %s-%s 的起始地址大于结束地址", from, to)
+	}
+	var targets []Target
+	for {
+		bits := from.BitLen()
+		for bits > 0 {
+			p := netip.PrefixFrom(from, bits-1)
+			if p.Masked().Addr() != from || lastAddr(p).Compare(to) > 0 {
+				break
+			}
+			bits--
+		}
+		p := netip.PrefixFrom(from, bits)
+		targets = append(targets, Target{Prefix: p})
+		last := lastAddr(p)
+		if last == to {
+			return targets, nil
+		}
+		from = last.Next()
+	}
+}
+
+// lastAddr 返回网段中的最后一个地址
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+var hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9_-]*[A-Za-z0-9])?)*\.?$`)
+
+func validHostname(s string) bool {
+	return len(s) <= 253 && hostnamePattern.MatchString(s)
+}
+
+// looksNumeric 判断主机名是否只由数字组成,这类输入是写错的 IPv4 地址而不是主机名
+func looksNumeric(s string) bool {
+	return strings.Trim(s, "0123456789.-") == ""
+}
+
+// normalize 按端口和地址排序,去掉重复条目和被同端口网段完整覆盖的条目.
+// 带主机名的条目即使被覆盖也会保留,以便记录主机名.
+func normalize(targets []Target) []Target {
+	slices.SortStableFunc(targets, func(a, b Target) int {
+		return cmp.Or(
+			cmp.Compare(a.Port, b.Port),
+			a.Prefix.Addr().Compare(b.Prefix.Addr()),
+			cmp.Compare(a.Prefix.Bits(), b.Prefix.Bits()),
+		)
+	})
+	var out []Target
+	outer := -1 // 最近一个未被覆盖的条目
+	for _, t := range targets {
+		if outer >= 0 && out[outer].Port == t.Port && covers(out[outer].Prefix, t.Prefix) {
+			if t.Host == "" || t.Host == out[outer].Host || slices.ContainsFunc(out, func(o Target) bool {
+				return o.Host == t.Host && o.Port == t.Port && o.Prefix == t.Prefix
+			}) {
+				continue
+			}
+			out = append(out, t)
+			continue
+		}
+		out = append(out, t)
+		outer = len(out) - 1
+	}
+	return out
+}
+
+// covers 判断网段 p 是否完整落在 outer 之内
+func covers(outer netip.Prefix, p netip.Prefix) bool {
+	return outer.Addr().Is4() == p.Addr().Is4() &&
+		outer.Bits() <= p.Bits() && outer.Contains(p.Addr())
+}
+
+// Prefixes 返回所有目标的网段
+func Prefixes(targets []Target) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(targets))
+	for _, t := range targets {
+		prefixes = append(prefixes, t.Prefix)
+	}
+	return prefixes
+}
@@ -0,0 +1,110 @@
+package target
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// targetStrings 返回目标的字符串形式,便于比较
+func targetStrings(targets []Target) []string {
+	var out []string
+	for _, t := range targets {
+		out = append(out, t.String())
+	}
+	return out
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"网段", "10.0.0.0/24", []string{"10.0.0.0/24"}},
+		{"网段归一化", "10.0.0.7/24", []string{"10.0.0.0/24"}},
+		{"单个地址", "192.0.2.1", []string{"192.0.2.1"}},
+		{"地址带端口", "192.0.2.1:8080", []string{"192.0.2.1:8080"}},
+		{"对齐的地址范围", "10.0.0.0-10.0.0.255", []string{"10.0.0.0/24"}},
+		{"不对齐的地址范围", "10.0.0.1 - 10.0.0.6", []string{"10.0.0.1", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6"}},
+		{"单地址范围", "10.0.0.9-10.0.0.9", []string{"10.0.0.9"}},
+		{"IPv6 地址", "2001:db8::1", []string{"2001:db8::1"}},
+		{"IPv6 网段", "2001:db8::/64", []string{"2001:db8::/64"}},
+		{"IPv6 带端口", "[2001:db8::1]:11434", []string{"[2001:db8::1]:11434"}},
+		{"IPv6 地址范围", "2001:db8::-2001:db8::3", []string{"2001:db8::/126"}},
+		{"IPv4 映射地址", "::ffff:192.0.2.1", []string{"192.0.2.1"}},
+		{"注释和空行", "# 办公网\n\n10.0.0.1 # 网关\n   \n", []string{"10.0.0.1"}},
+		{"主机名", "localhost", []string{"localhost(127.0.0.1)"}},
+		{"主机名带端口", "localhost:8080", []string{"localhost(127.0.0.1:8080)"}},
+		{"去重", "10.0.0.1\n10.0.0.1", []string{"10.0.0.1"}},
+		{"去掉被覆盖的条目", "10.0.0.5\n10.0.0.0/24\n10.0.0.0/28", []string{"10.0.0.0/24"}},
+		{"不同端口不合并", "10.0.0.0/24\n10.0.0.5:8080", []string{"10.0.0.0/24", "10.0.0.5:8080"}},
+		{"被覆盖的主机名保留", "127.0.0.0/8\nlocalhost", []string{"127.0.0.0/8", "localhost(127.0.0.1)"}},
+		{"按端口和地址排序", "10.0.0.2\n2001:db8::1\n10.0.0.1\n10.0.0.1:80", []string{"10.0.0.1", "10.0.0.2", "2001:db8::1", "10.0.0.1:80"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := Read(context.Background(), strings.NewReader(tt.input), "ip.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := targetStrings(targets)
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Fatalf("Read(%q) = %v, 期望 %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLineNumbers(t *testing.T) {
+	targets, err := Read(context.Background(), strings.NewReader("# 注释\n10.0.0.2\n\n10.0.0.1\n"), "ip.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 || targets[0].Line != 4 || targets[1].Line != 2 {
+		t.Fatalf("Targets = %+v", targets)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"10.0.0.0/33", "无法解析网段"},
+		{"10.0.0.0/24:80", "只有单个地址或主机名可以指定端口"},
+		{"10.0.0.1-10.0.0.5:80", "只有单个地址或主机名可以指定端口"},
+		{"10.0.0.9-10.0.0.1", "起始地址大于结束地址"},
+		{"10.0.0.1-2001:db8::1", "不属于同一协议"},
+		{"fe80::1%eth0", "无法识别的目标"},
+		{"fe80::1%eth0/64", "无法解析网段"},
+		{"10.0.0.1:0", "端口必须在 1-65535 之间"},
+		{"localhost:70000", "端口必须在 1-65535 之间"},
+		{"10.0.0.256", "无法解析地址"},
+		{"10.0.1", "无法解析地址"},
+		{"not a host", "无法识别的目标"},
+		{"-bad.example", "无法识别的目标"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			_, err := Read(context.Background(), strings.NewReader(tt.line), "ip.txt")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Read(%q) err = %v, 期望包含 %q", tt.line, err, tt.want)
+			}
+			if !strings.HasPrefix(err.Error(), "ip.txt:1: ") {
+				t.Errorf("错误缺少文件名和行号: %v", err)
+			}
+		})
+	}
+}
+
+func TestReadCollectsProblems(t *testing.T) {
+	_, err := Read(context.Background(), strings.NewReader("10.0.0.1\nbad target\n10.0.0.0/40\n"), "ip.txt")
+	if err == nil {
+		t.Fatal("期望返回错误")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "ip.txt:2: ") || !strings.HasPrefix(lines[1], "ip.txt:3: ") {
+		t.Fatalf("err = %v", err)
+	}
+}
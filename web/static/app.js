@@ -0,0 +1,270 @@
+// 结果浏览页面,所有数据来自 /api 下的 REST 接口
+"use strict";
+
+const tokenInput = document.getElementById("token");
+tokenInput.value = localStorage.getItem("apiToken") || "";
+tokenInput.addEventListener("change", () => {
+  localStorage.setItem("apiToken", tokenInput.value);
+  refresh();
+});
+
+// withToken 为无法设置请求头的 EventSource 和下载链接附加令牌参数
+function withToken(url) {
+  const token = tokenInput.value;
+  if (!token) return url;
+  return url + (url.includes("?") ? "&" : "?") + "token=" + encodeURIComponent(token);
+}
+
+async function api(path, options = {}) {
+  const headers = Object.assign({}, options.headers);
+  if (tokenInput.value) headers.Authorization = "Bearer " + tokenInput.value;
+  const resp = await fetch(path, Object.assign({}, options, { headers }));
+  const body = await resp.json().catch(() => ({}));
+  if (!resp.ok) throw new Error(body.error || resp.statusText);
+  return body;
+}
+
+// el 创建元素,子节点中的字符串作为文本插入,避免解释结果中的 HTML
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  for (const [key, value] of Object.entries(attrs || {})) {
+    if (key.startsWith("on")) node.addEventListener(key.slice(2), value);
+    else node.setAttribute(key, value);
+  }
+  for (const child of children) {
+    if (child == null) continue;
+    node.append(child instanceof Node ? child : String(child));
+  }
+  return node;
+}
+
+// address 返回主机地址,指定了端口的主机为 IP:端口
+function address(h) {
+  if (!h.port) return h.ip;
+  return h.ip.includes(":") ? `[${h.ip}]:${h.port}` : `${h.ip}:${h.port}`;
+}
+
+// addressKey 将地址转换为可按字符串比较的定长形式,IPv4 排在 IPv6 之前
+function addressKey(value) {
+  const m = value.match(/^\[(.+)\]:(\d+)$/) || value.match(/^([\d.]+):(\d+)$/);
+  const ip = m ? m[1] : value;
+  const port = (m ? m[2] : "").padStart(5, "0");
+  if (!ip.includes(":")) {
+    return "4" + ip.split(".").map((p) => p.padStart(3, "0")).join(".") + port;
+  }
+  const [head, tail] = ip.split("::");
+  const h = head ? head.split(":") : [];
+  const t = tail ? tail.split(":") : [];
+  const groups = tail === undefined ? h : [...h, ...Array(8 - h.length - t.length).fill("0"), ...t];
+  return "6" + groups.map((g) => g.padStart(4, "0")).join(":") + port;
+}
+
+function formatTime(value) {
+  if (!value || value.startsWith("0001-")) return "";
+  return new Date(value).toLocaleString();
+}
+
+// 可排序表格:点击表头切换升序和降序
+const tables = {};
+
+function sortableTable(id, render) {
+  const table = document.getElementById(id);
+  const state = { rows: [], key: null, type: null, desc: false };
+  table.querySelectorAll("th[data-key]").forEach((th) => {
+    th.addEventListener("click", () => {
+      state.desc = state.key === th.dataset.key ? !state.desc : th.dataset.type === "number";
+      state.key = th.dataset.key;
+      state.type = th.dataset.type;
+      table.querySelectorAll("th").forEach((h) => h.classList.remove("asc", "desc"));
+      th.classList.add(state.desc ? "desc" : "asc");
+      draw();
+    });
+  });
+  function draw() {
+    const rows = state.rows.slice();
+    if (state.key) {
+      rows.sort((a, b) => {
+        let x = a[state.key], y = b[state.key];
+        if (state.type === "address") [x, y] = [addressKey(x), addressKey(y)];
+        const cmp = typeof x === "number" ? x - y
+          : state.type === "address" ? (x < y ? -1 : x > y ? 1 : 0)
+          : String(x).localeCompare(String(y));
+        return state.desc ? -cmp : cmp;
+      });
+    }
+    table.tBodies[0].replaceChildren(...rows.map(render));
+  }
+  tables[id] = (rows) => { state.rows = rows; draw(); };
+}
+
+sortableTable("hosts", (h) => el("tr", {},
+  el("td", { class: "link", onclick: () => showHost(h.ip), title: h.hostname }, h.ip),
+  el("td", {}, h.version),
+  el("td", {}, h.models),
+  el("td", {}, h.running),
+  el("td", { class: "risk-" + h.level }, h.risk + " " + h.level),
+  el("td", {}, formatTime(h.probed_at)),
+  el("td", {}, h.job)));
+
+sortableTable("models", (m) => el("tr", {},
+  el("td", { class: "link", onclick: () => showHost(m.ip) }, m.ip),
+  el("td", {}, m.name),
+  el("td", {}, m.skip_reason ? `${m.status}(${m.skip_reason})` : m.status),
+  el("td", {}, m.tokens_per_sec.toFixed(1)),
+  el("td", {}, m.first_token_ms.toFixed(0)),
+  el("td", {}, m.size.toFixed(1)),
+  el("td", {}, formatTime(m.benchmarked_at))));
+
+function filterQuery() {
+  const params = new URLSearchParams();
+  for (const [key, value] of new FormData(document.getElementById("filter-form"))) {
+    if (value) params.set(key, value);
+  }
+  return params.toString();
+}
+
+async function loadResults() {
+  const query = filterQuery();
+  const [hosts, models] = await Promise.all([
+    api("/api/hosts?limit=1000&" + query),
+    api("/api/models?limit=1000&" + query),
+  ]);
+  tables.hosts(hosts.map((h) => ({
+    ip: address(h), hostname: h.hostname || "", version: h.version || "", models: h.models.length, running: (h.running || []).length,
+    risk: h.risk.score, level: h.risk.level, probed_at: h.probed_at, job: h.job,
+  })));
+  tables.models(models.map((m) => ({
+    ip: address(m), name: m.name, status: m.status, skip_reason: m.skip_reason || "",
+    tokens_per_sec: m.tokens_per_sec, first_token_ms: m.first_token_delay_ns / 1e6,
+    size: (m.size || 0) / 1e9, benchmarked_at: m.benchmarked_at || "",
+  })));
+  document.getElementById("download-csv").href = withToken("/api/export?format=csv&" + query);
+  document.getElementById("download-json").href = withToken("/api/export?format=json&" + query);
+}
+
+// 进行中的扫描通过事件流实时更新进度,结束后刷新结果.
+// 收到 done 的运行不再订阅,避免运行列表尚未更新时反复重连.
+const streams = {};
+const finished = new Set();
+
+function watchRun(id) {
+  if (streams[id] || finished.has(id)) return;
+  const source = new EventSource(withToken(`/api/scans/${encodeURIComponent(id)}/events`));
+  streams[id] = source;
+  const update = (progress) => {
+    const cell = document.getElementById("progress-" + id);
+    if (!cell || !progress) return;
+    const bar = el("progress", { max: Math.max(progress.discovered, 1), value: progress.completed });
+    cell.replaceChildren(bar, ` ${progress.completed}/${progress.discovered}`);
+  };
+  source.addEventListener("progress", (e) => update(JSON.parse(e.data)));
+  source.addEventListener("done", () => {
+    source.close();
+    delete streams[id];
+    finished.add(id);
+    refresh();
+  });
+  source.onerror = () => {
+    source.close();
+    delete streams[id];
+  };
+}
+
+async function loadRuns() {
+  const runs = await api("/api/scans");
+  const rows = runs.slice(0, 20).map((r) => {
+    const running = r.status === "running";
+    if (running) watchRun(r.id);
+    return el("tr", {},
+      el("td", {}, r.id),
+      el("td", {}, r.job),
+      el("td", { title: r.error || "" }, r.status),
+      el("td", { id: "progress-" + r.id }, running ? "…" : ""),
+      el("td", {}, r.hosts),
+      el("td", {}, r.models),
+      el("td", {}, formatTime(r.started_at)),
+      el("td", {}, running && r.job === "api"
+        ? el("button", { onclick: () => cancelRun(r.id) }, "取消")
+        : null));
+  });
+  document.querySelector("#runs tbody").replaceChildren(...rows);
+}
+
+async function cancelRun(id) {
+  try {
+    await api(`/api/scans/${encodeURIComponent(id)}`, { method: "DELETE" });
+  } catch (err) {
+    alert(err.message);
+  }
+}
+
+async function showHost(ip) {
+  const detail = await api("/api/hosts/" + encodeURIComponent(ip));
+  const history = [];
+  for (const h of detail.history) {
+    for (const m of h.models) {
+      if (!m.benchmarked_at) continue;
+      history.push(el("tr", {},
+        el("td", {}, formatTime(m.benchmarked_at)), el("td", {}, m.name), el("td", {}, m.status),
+        el("td", {}, m.tokens_per_sec.toFixed(1)), el("td", {}, (m.first_token_delay_ns / 1e6).toFixed(0)),
+        el("td", {}, h.run_id)));
+    }
+  }
+  const table = (headers, rows) => el("table", {},
+    el("thead", {}, el("tr", {}, ...headers.map((h) => el("th", {}, h)))),
+    el("tbody", {}, ...rows));
+
+  document.getElementById("host-detail").replaceChildren(
+    el("h2", {}, address(detail)),
+    detail.hostname ? el("p", {}, "主机名: " + detail.hostname) : null,
+    el("p", {}, `版本: ${detail.version || "未知"}  ·  最近探测: ${formatTime(detail.probed_at)}  ·  任务: ${detail.job}`),
+    el("h3", {}, "风险评分"),
+    el("p", { class: "risk-" + detail.risk.level }, `${detail.risk.score} (${detail.risk.level})`),
+    el("ul", {}, ...detail.risk.reasons.map((r) => el("li", {}, r))),
+    el("h3", {}, "模型"),
+    table(["模型名称", "状态", "Tokens/s", "许可证", "参数量", "已加载"], detail.models.map((m) => el("tr", {},
+      el("td", {}, m.name), el("td", {}, m.status), el("td", {}, m.tokens_per_sec.toFixed(1)),
+      el("td", {}, m.details ? m.details.license : ""), el("td", {}, m.details ? m.details.parameter_size : ""),
+      el("td", {}, (detail.running || []).some((r) => r.name === m.name) ? "是" : "否")))),
+    el("h3", {}, "性能测试历史"),
+    history.length ? table(["测试时间", "模型名称", "状态", "Tokens/s", "首Token延迟(ms)", "运行ID"], history) : el("p", {}, "暂无")
+  );
+  document.getElementById("host-dialog").showModal();
+}
+
+document.getElementById("filter-form").addEventListener("submit", (e) => {
+  e.preventDefault();
+  loadResults().catch(showError);
+});
+
+document.getElementById("scan-form").addEventListener("submit", async (e) => {
+  e.preventDefault();
+  const message = document.getElementById("scan-message");
+  const targets = document.getElementById("targets").value.split(/\s+/).filter(Boolean);
+  try {
+    const run = await api("/api/scans", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ targets, disable_bench: document.getElementById("disable-bench").checked }),
+    });
+    message.className = "message";
+    message.textContent = "已提交: " + run.id;
+    loadRuns().catch(showError);
+  } catch (err) {
+    message.className = "message error";
+    message.textContent = err.message;
+  }
+});
+
+function showError(err) {
+  const message = document.getElementById("scan-message");
+  message.className = "message error";
+  message.textContent = err.message;
+}
+
+function refresh() {
+  loadRuns().catch(showError);
+  loadResults().catch(showError);
+}
+
+refresh();
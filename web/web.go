@@ -0,0 +1,20 @@
+// Package web 提供嵌入到程序中的结果浏览页面,页面数据全部来自 api 包提供的 REST 接口.
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var static embed.FS
+
+// Handler 返回静态页面处理器,应挂载在站点根路径
+func Handler() http.Handler {
+	sub, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServerFS(sub)
+}